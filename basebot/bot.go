@@ -0,0 +1,74 @@
+package basebot
+
+import (
+	"context"
+
+	"paintbot-client/models"
+)
+
+// Bot is a paintbot played by a Client.
+// OnMapUpdate is called for every map update, see AnytimeMoveFunc for how propose and ctx work.
+//
+// A Bot can also implement any of the optional handler interfaces below to be told about the other
+// events from the server. Handlers are called from the client's receive loop, so they should return quickly.
+type Bot interface {
+	OnMapUpdate(ctx context.Context, event models.MapUpdateEvent, propose func(action models.Action))
+}
+
+// MoveFunc adapts a plain calculateMove function to a Bot
+type MoveFunc func(event models.MapUpdateEvent) models.Action
+
+func (f MoveFunc) OnMapUpdate(ctx context.Context, event models.MapUpdateEvent, propose func(action models.Action)) {
+	propose(f(event))
+}
+
+func (f AnytimeMoveFunc) OnMapUpdate(ctx context.Context, event models.MapUpdateEvent, propose func(action models.Action)) {
+	f(ctx, event, propose)
+}
+
+// NewGameHandler is called once for every game the client takes part in, e.g. each game of a tournament,
+// before OnGameStarting. Bots playing several games should reset their per game state here.
+type NewGameHandler interface {
+	OnNewGame(game GameInfo)
+}
+
+// GameStartingHandler is told the size and settings of each game before the first map update
+type GameStartingHandler interface {
+	OnGameStarting(event models.GameStartingEvent)
+}
+
+// GameLinkHandler gets the link where the game can be viewed
+type GameLinkHandler interface {
+	OnGameLink(event models.GameLinkEvent)
+}
+
+// GameResultHandler gets the final ranking of each game
+type GameResultHandler interface {
+	OnGameResult(event models.GameResultEvent)
+}
+
+// GameEndedHandler is called when a game has ended, with the final map
+type GameEndedHandler interface {
+	OnGameEnded(event models.GameEndedEvent)
+}
+
+// TournamentEndedHandler gets the results of the whole tournament
+type TournamentEndedHandler interface {
+	OnTournamentEnded(event models.TournamentEndedEvent)
+}
+
+// InvalidMessageHandler is told when the server rejected a message sent by the client.
+// It is called before the client acts according to its InvalidMessagePolicy.
+type InvalidMessageHandler interface {
+	OnInvalidMessage(err *InvalidMessageError)
+}
+
+// ReconnectHandler is called before each attempt to reconnect after the connection was lost
+type ReconnectHandler interface {
+	OnReconnect(attempt int, cause error)
+}
+
+// UnknownMessageHandler gets the raw messages of types the client does not handle, e.g. new events from a newer server
+type UnknownMessageHandler interface {
+	OnUnknownMessage(msgType string, raw []byte)
+}
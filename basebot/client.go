@@ -0,0 +1,408 @@
+package basebot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/models"
+	"paintbot-client/utilities/timeHelper"
+)
+
+// Client plays paintbot on behalf of a single player.
+// Each Client owns its own connection so several bots can be run side by side in one process.
+type Client struct {
+	PlayerName   string
+	GameMode     models.GameMode
+	GameSettings *models.GameSettings
+	Bot          Bot
+	Connection   ConnectionOptions
+	// Recorder, if set, gets every message received and sent, see NewRecorder
+	Recorder *Recorder
+	// Dial opens the connection to play over, when nil a websocket to the server described by Connection is used
+	Dial      func(ctx context.Context) (MessageSource, error)
+	Reconnect RetryPolicy
+	// FallbackAction is sent when the bot has not proposed a move in time, defaults to STAY
+	FallbackAction models.Action
+	// InvalidMessagePolicy decides whether to keep playing when the server rejects a message, defaults to abort
+	InvalidMessagePolicy InvalidMessagePolicy
+	// Strict makes unknown messages from the server stop the client instead of being ignored
+	Strict bool
+	// HeartbeatInterval is the time between heartbeats, randomly varied by the HeartbeatJitter fraction.
+	// HeartbeatJitter is limited to [0, 1) so there is always some time between heartbeats.
+	HeartbeatInterval time.Duration
+	HeartbeatJitter   float64
+	// MaxMissedHeartbeats is the number of unanswered heartbeats in a row before the connection
+	// is considered dead, 0 disables the check
+	MaxMissedHeartbeats int
+	// WriteTimeout is the longest a single message may take to write before the connection is considered dead
+	WriteTimeout time.Duration
+	// MoveSafetyMargin is kept free in each tick on top of the measured network round trip
+	MoveSafetyMargin time.Duration
+	// WaitForBot makes the client wait for the bot to return before sending each move instead of sending
+	// FallbackAction at the tick deadline, so slow bots see every tick. Only useful without a live server, see Replay.
+	WaitForBot bool
+	// EliminationTimeout ends a tournament for the player when no new game has started this long after its last game.
+	// The server never tells a player it is out of a tournament, it only stops starting games for it,
+	// so with the default of 0 the client plays until the TournamentEndedEvent.
+	EliminationTimeout time.Duration
+
+	session      *session
+	calculating  chan struct{}
+	mux          sync.Mutex
+	playerID     *string
+	tickDuration time.Duration
+	game         GameInfo
+	latency      latencyTracker
+	stats        statsCounter
+	// when the last game ended, zero while a game is being played
+	gameEnded time.Time
+}
+
+// NewClient creates a client connecting to the public server,
+// desiredGameSettings can be nil to get the server defaults
+func NewClient(playerName string, gameMode models.GameMode, desiredGameSettings *models.GameSettings, bot Bot) *Client {
+	return &Client{
+		PlayerName:   playerName,
+		GameMode:     gameMode,
+		GameSettings: desiredGameSettings,
+		Bot:          bot,
+		Connection:   DefaultConnectionOptions(),
+		Reconnect:    DefaultRetryPolicy(),
+
+		FallbackAction:      models.Stay,
+		HeartbeatInterval:   30 * time.Second,
+		HeartbeatJitter:     0.1,
+		MaxMissedHeartbeats: 3,
+		WriteTimeout:        5 * time.Second,
+		MoveSafetyMargin:    20 * time.Millisecond,
+	}
+}
+
+// Run connects to the server and plays until the game (training) or tournament has ended.
+// The server sends no event when a player is eliminated from a tournament, so a tournament is only left early
+// if EliminationTimeout is set and no new game starts in time, then Run returns nil.
+// If the connection is lost the client reconnects according to the Reconnect policy.
+// The server has no way of resuming a session, so after a reconnect the player is registered again.
+// When ctx is cancelled the connection is closed and an error with Op OpCanceled is returned.
+// A non nil error is always of type *Error and describes why the client had to stop.
+func (c *Client) Run(ctx context.Context) error {
+	c.calculating = make(chan struct{}, 1)
+	attempt := 0
+	for {
+		progressed, err := c.runSession(ctx)
+		if err == nil {
+			return nil
+		}
+		if progressed {
+			attempt = 0
+		}
+		if !isConnectionError(err) || attempt >= c.Reconnect.MaxAttempts {
+			return err
+		}
+
+		attempt++
+		backoff := c.Reconnect.Backoff(attempt)
+		log.Warnf("Connection lost: %v, reconnecting in %s (attempt %d/%d)\n", err, backoff, attempt, c.Reconnect.MaxAttempts)
+		if !sleep(ctx, backoff) {
+			return &Error{Op: OpCanceled, Err: ctx.Err()}
+		}
+		if h, ok := c.Bot.(ReconnectHandler); ok {
+			h.OnReconnect(attempt, err)
+		}
+	}
+}
+
+// runSession plays over a single connection until it fails or the client is done,
+// progressed tells whether the session got anywhere, see session.progressed
+func (c *Client) runSession(ctx context.Context) (progressed bool, err error) {
+	source, err := c.dial(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, &Error{Op: OpCanceled, Err: ctx.Err()}
+		}
+		return false, err
+	}
+	s := newSession(source, c.WriteTimeout, c.Recorder, &c.latency)
+	c.session = s
+	defer s.close()
+
+	go c.heartbeat(s)
+
+	// closing the connection is the only way to interrupt a blocking read
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.close()
+		case <-s.done:
+		}
+	}()
+
+	if err := c.registerPlayer(); err != nil {
+		return false, err
+	}
+
+	for {
+		timeout, stop := c.eliminationTimeout()
+		batch, eliminated := s.nextBatch(timeout)
+		stop()
+		if eliminated {
+			log.Infof("No new game within %s, leaving the tournament\n", c.EliminationTimeout)
+			return s.progressed(), nil
+		}
+		if batch == nil {
+			err = &Error{Op: OpReceive, Err: errSessionClosed}
+		}
+		for i := range batch {
+			if supersededUpdate(batch, i) {
+				c.stats.update(func(stats *Stats) {
+					stats.MapUpdates++
+					stats.SkippedTicks++
+				})
+				log.Warnf("Skipping tick %d, a newer map update is waiting\n", batch[i].msg.(models.MapUpdateEvent).GameTick)
+				continue
+			}
+
+			var done bool
+			done, err = c.recv(ctx, batch[i])
+			if done && err == nil {
+				return s.progressed(), nil
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		if ctx.Err() != nil {
+			return s.progressed(), &Error{Op: OpCanceled, Err: ctx.Err()}
+		}
+		if failure := s.failure(); failure != nil {
+			return s.progressed(), failure
+		}
+		if err != nil {
+			return s.progressed(), err
+		}
+	}
+}
+
+func (c *Client) dial(ctx context.Context) (MessageSource, error) {
+	if c.Dial == nil {
+		return getWebsocketConnection(ctx, c.Connection, c.GameMode)
+	}
+	source, err := c.Dial(ctx)
+	if err != nil {
+		return nil, &Error{Op: OpDial, Err: err}
+	}
+	return source, nil
+}
+
+// Stats returns what the client has done with the map updates received so far
+func (c *Client) Stats() Stats {
+	return c.stats.get()
+}
+
+// Latency returns the latest measurements of the connection to the server
+func (c *Client) Latency() LatencyStats {
+	return c.latency.get()
+}
+
+// PlayerID returns the id assigned by the server, or nil if the player is not registered yet
+func (c *Client) PlayerID() *string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.playerID
+}
+
+func (c *Client) recv(ctx context.Context, in inbound) (done bool, err error) {
+	if in.err != nil {
+		return false, in.err
+	}
+	msg, decoded, received := in.raw, in.msg, in.received
+
+	if err := in.decodeErr; err != nil {
+		if unknown, ok := err.(*models.UnknownMessageTypeError); ok {
+			return false, c.handleUnknownMessage(unknown.Type, msg)
+		}
+		return false, &Error{Op: OpDecode, Err: err}
+	}
+
+	switch event := decoded.(type) {
+	case models.InvalidMessage:
+		invalidErr := newInvalidMessageError(event)
+		if h, ok := c.Bot.(InvalidMessageHandler); ok {
+			h.OnInvalidMessage(invalidErr)
+		}
+		if c.InvalidMessagePolicy == AbortOnInvalidMessage {
+			return false, &Error{Op: OpInvalidMessage, Err: invalidErr}
+		}
+		log.Warnf("%v\n", invalidErr)
+	case models.PlayerRegisteredEvent:
+		c.setTickDuration(event.GameSettings)
+		c.mux.Lock()
+		c.playerID = event.ReceivingPlayerID
+		c.mux.Unlock()
+		c.session.markRegistered()
+		if err := c.sendClientInfo(); err != nil {
+			return false, err
+		}
+		log.Infof("Player registered")
+		if err := c.startGame(); err != nil {
+			return false, err
+		}
+	case models.GameLinkEvent:
+		log.Infof("Game can be viewed at: %s\n", event.URL)
+		if h, ok := c.Bot.(GameLinkHandler); ok {
+			h.OnGameLink(event)
+		}
+	case models.GameStartingEvent:
+		game := c.beginGame(event)
+		log.Infof("Game %d started\n", game.Number)
+		if h, ok := c.Bot.(GameStartingHandler); ok {
+			h.OnGameStarting(event)
+		}
+	case models.MapUpdateEvent:
+		c.session.playing = true
+		c.stats.update(func(stats *Stats) { stats.MapUpdates++ })
+		if event.GameTick%10 == 0 {
+			log.Infof("Game tick: %d\n", event.GameTick)
+		}
+		if err := c.handleMapUpdate(ctx, received, event); err != nil {
+			return false, err
+		}
+	case models.GameResultEvent:
+		log.Infof("### Game Results ###\n")
+		for _, player := range event.PlayerRanks {
+			log.Infof("%d: %s - %d\n", player.Rank, player.PlayerName, player.Points)
+		}
+		if h, ok := c.Bot.(GameResultHandler); ok {
+			h.OnGameResult(event)
+		}
+	case models.GameEndedEvent:
+		if event.ReceivingPlayerID != nil && event.PlayerWinnerID == *event.ReceivingPlayerID {
+			log.Info("You won the game")
+		}
+		c.gameEnded = time.Now()
+		if h, ok := c.Bot.(GameEndedHandler); ok {
+			h.OnGameEnded(event)
+		}
+		if c.doneAfterGame() {
+			return true, nil
+		}
+	case models.TournamentEndedEvent:
+		log.Infof("### Tournament Ended ###")
+		for _, player := range event.GameResult {
+			log.Infof("%s - %d\n", player.Name, player.Points)
+		}
+		if h, ok := c.Bot.(TournamentEndedHandler); ok {
+			h.OnTournamentEnded(event)
+		}
+		return true, nil
+	case models.HeartBeatResponse:
+		c.session.heartbeatResponded()
+		c.latency.heartbeatResponded(received, event.Timestamp)
+		log.Debugf("Latency: %+v\n", c.latency.get())
+	default:
+		return false, c.handleUnknownMessage(decoded.MessageType(), msg)
+	}
+	return false, nil
+}
+
+// handleUnknownMessage passes messages the client cannot handle on to the bot,
+// they are only treated as errors in Strict mode
+func (c *Client) handleUnknownMessage(msgType string, msg []byte) error {
+	if c.Strict {
+		return &Error{Op: OpUnknownMessage, Err: &UnknownMessageError{Type: msgType, Raw: msg}}
+	}
+	log.Warnf("Ignoring unknown message of type %s\n", msgType)
+	if h, ok := c.Bot.(UnknownMessageHandler); ok {
+		h.OnUnknownMessage(msgType, msg)
+	}
+	return nil
+}
+
+func (c *Client) handleMapUpdate(ctx context.Context, received time.Time, event models.MapUpdateEvent) error {
+	ctx = context.WithValue(ctx, latencyKey{}, c.latency.get())
+	action := c.calculateMoveBefore(ctx, c.moveDeadline(received), event)
+	if c.session.isStale(event.GameID, event.GameTick) {
+		c.stats.update(func(stats *Stats) { stats.StaleMoves++ })
+		log.Warnf("Not sending move for tick %d, a newer map update has arrived\n", event.GameTick)
+		return nil
+	}
+	if err := c.sendMove(received, event, action); err != nil {
+		if !errors.Is(err, errWriteQueueFull) {
+			return err
+		}
+		log.Warnf("Dropping move for tick %d: %v\n", event.GameTick, err)
+		return nil
+	}
+	c.stats.update(func(stats *Stats) { stats.MovesSent++ })
+	return nil
+}
+
+func (c *Client) registerPlayer() error {
+	registerMSG := &models.RegisterPlayerEvent{
+		Type:              models.TypeRegisterPlayer,
+		PlayerName:        c.PlayerName,
+		GameSettings:      c.GameSettings,
+		ReceivingPlayerID: nil,
+		Timestamp:         timeHelper.Now(),
+	}
+
+	log.Debugf("Registering player: %v\n", registerMSG)
+	if err := c.session.send(registerMSG); err != nil {
+		var sendErr *Error
+		if errors.As(err, &sendErr) {
+			err = sendErr.Err
+		}
+		return &Error{Op: OpRegister, Err: err}
+	}
+	return nil
+}
+
+// sendClientInfo tells the server about the client, sent with the request type se.cygni.paintbot.api.request.ClientInfo
+func (c *Client) sendClientInfo() error {
+	clientInfoMSG := &models.ClientInfoMSG{
+		Type:                   models.TypeClientInfo,
+		Language:               "Go",
+		LanguageVersion:        runtime.Version(),
+		OperatingSystem:        runtime.GOOS,
+		OperatingSystemVersion: "",
+		ClientVersion:          "0.3",
+		ReceivingPlayerID:      c.PlayerID(),
+		Timestamp:              timeHelper.Now(),
+	}
+	return c.session.send(clientInfoMSG)
+}
+
+func (c *Client) startGame() error {
+	startGame := &models.StartGameEvent{
+		Type:              models.TypeStartGame,
+		ReceivingPlayerID: nil,
+		Timestamp:         timeHelper.Now(),
+	}
+
+	return c.session.send(startGame)
+}
+
+func (c *Client) sendMove(received time.Time, updateEvent models.MapUpdateEvent, action models.Action) error {
+	moveEvent := &models.RegisterMoveEvent{
+		Type:              models.TypeRegisterMove,
+		GameID:            updateEvent.GameID,
+		GameTick:          updateEvent.GameTick,
+		Action:            string(action),
+		ReceivingPlayerID: updateEvent.ReceivingPlayerID,
+		Timestamp:         timeHelper.Now(),
+	}
+	if log.IsLevelEnabled(log.DebugLevel) {
+		if marshal, err := json.Marshal(moveEvent); err == nil {
+			log.Debugf("send action: %s\n", marshal)
+		}
+	}
+
+	return c.session.sendMove(moveEvent, received)
+}
@@ -0,0 +1,117 @@
+package basebot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"paintbot-client/models"
+)
+
+func TestClient_cancelStopsRun(t *testing.T) {
+	source := newIdleSource(registered, true)
+	client := NewClient("test", models.Training, nil, MoveFunc(func(models.MapUpdateEvent) models.Action { return models.Stay }))
+	client.Dial = func(ctx context.Context) (MessageSource, error) { return source, nil }
+	client.HeartbeatInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- client.Run(ctx) }()
+	// the client is now waiting for a map update that never comes
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after ctx was canceled")
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Op != OpCanceled || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled error, got %v", err)
+	}
+	select {
+	case <-source.closed:
+	default:
+		t.Error("expected the connection to be closed")
+	}
+
+	heartbeats := source.sentHeartbeats()
+	if heartbeats == 0 {
+		t.Fatal("expected heartbeats before canceling")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if after := source.sentHeartbeats(); after != heartbeats {
+		t.Errorf("expected heartbeats to stop, %d sent after Run returned", after-heartbeats)
+	}
+}
+
+func TestClient_fallbackWhenTooSlow(t *testing.T) {
+	client, source := replayClient(recording(1), AnytimeMoveFunc(func(ctx context.Context, event models.MapUpdateEvent, propose func(models.Action)) {
+		<-ctx.Done()
+		propose(models.Up)
+	}))
+	client.FallbackAction = models.Down
+
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	moves := source.moves()
+	if len(moves) != 1 || moves[0].Action != string(models.Down) {
+		t.Errorf("expected fallback move, got %+v", moves)
+	}
+}
+
+func TestClient_unknownMessages(t *testing.T) {
+	stream := recording(1, `{"type":"se.cygni.paintbot.api.event.SomethingNew"}`)
+	bot := MoveFunc(func(event models.MapUpdateEvent) models.Action { return models.Stay })
+
+	client, _ := replayClient(stream, bot)
+	if err := client.Run(context.Background()); err != nil {
+		t.Errorf("unknown message should be ignored, got %v", err)
+	}
+
+	client, _ = replayClient(stream, bot)
+	client.Strict = true
+	err := client.Run(context.Background())
+	var clientErr *Error
+	if !errors.As(err, &clientErr) || clientErr.Op != OpUnknownMessage {
+		t.Errorf("expected unknown message error in strict mode, got %v", err)
+	}
+}
+
+func TestClient_invalidMessage(t *testing.T) {
+	invalid := `{"type":"se.cygni.paintbot.api.exception.InvalidMessage","errorMessage":"late",` +
+		`"receivedMessage":"{\"type\":\"se.cygni.paintbot.api.request.RegisterMove\",\"gameTick\":12}"}`
+	bot := MoveFunc(func(event models.MapUpdateEvent) models.Action { return models.Stay })
+
+	client, _ := replayClient(recording(1, invalid), bot)
+	err := client.Run(context.Background())
+	var invalidErr *InvalidMessageError
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("expected invalid message error, got %v", err)
+	}
+	if tick, ok := invalidErr.Tick(); !ok || tick != 12 {
+		t.Errorf("expected tick 12, got %d", tick)
+	}
+
+	client, _ = replayClient(recording(1, invalid), bot)
+	client.InvalidMessagePolicy = ContinueOnInvalidMessage
+	if err := client.Run(context.Background()); err != nil {
+		t.Errorf("expected the game to continue, got %v", err)
+	}
+}
+
+func TestClient_registerOnClosedSession(t *testing.T) {
+	client := NewClient("test", models.Training, nil, nil)
+	client.session = newSession(newIdleSource("", true), time.Second, nil, nil)
+	client.session.close()
+
+	err := client.registerPlayer()
+	var e *Error
+	if !errors.As(err, &e) || e.Op != OpRegister || e.Err != errSessionClosed {
+		t.Errorf("expected register error wrapping the closed session, got %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package basebot
 
 import (
-	"net/url"
+	"context"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
@@ -9,26 +11,155 @@ import (
 	"paintbot-client/models"
 )
 
-func getWebsocketConnection(gameMode models.GameMode) *websocket.Conn {
-	var u = url.URL{
-		Scheme: "ws",
-		Host:   "server.paintbot.cygni.se:80",
-		//Host: "localhost:8080",
-		Path: string(gameMode),
+// MessageSource is the connection a Client plays over.
+// Normally it is a websocket to the server, but it can be anything delivering server messages, e.g. a recording.
+type MessageSource interface {
+	// ReadMessage blocks until the next message from the server is available
+	ReadMessage() ([]byte, error)
+	// WriteMessage sends a message to the server, failing if it is not written before deadline.
+	// A zero deadline means no deadline.
+	WriteMessage(data []byte, deadline time.Time) error
+	// Close ends the connection, any blocked ReadMessage must return an error
+	Close() error
+}
+
+// session is a single connection to the server, a new one is created for every reconnect
+type session struct {
+	source       MessageSource
+	recorder     *Recorder
+	latency      *latencyTracker
+	moveQueue    chan interface{}
+	normalQueue  chan interface{}
+	inbound      chan inbound
+	done         chan struct{}
+	closeOnce    sync.Once
+	registered   bool
+	registeredCh chan struct{}
+	started      time.Time
+	// playing is set once a map update has been received
+	playing bool
+
+	// the newest map update received
+	latestMux    sync.Mutex
+	latestGameID string
+	latestTick   int
+
+	unansweredHeartbeats int32
+
+	errMux sync.Mutex
+	err    error
+}
+
+const closeFrameTimeout = time.Second
+
+// a session lasting this long counts as progress even without any map updates, e.g. waiting for a tournament to start
+const stableSessionDuration = time.Minute
+
+// websocketSource is a MessageSource connected to a paintbot server
+type websocketSource struct {
+	conn *websocket.Conn
+}
+
+func getWebsocketConnection(ctx context.Context, opts ConnectionOptions, gameMode models.GameMode) (MessageSource, error) {
+	if err := opts.validate(); err != nil {
+		return nil, &Error{Op: OpDial, Err: err}
+	}
+	u := opts.URL(gameMode)
+
+	dialer := &websocket.Dialer{
+		Proxy:            opts.Proxy,
+		HandshakeTimeout: opts.HandshakeTimeout,
+		TLSClientConfig:  opts.TLSConfig,
 	}
 
 	log.Debugf("connecting to: %s\n", u.String())
-	conn, _, connectionError := websocket.DefaultDialer.Dial(u.String(), nil)
+	conn, _, connectionError := dialer.DialContext(ctx, u.String(), opts.Header)
 	if connectionError != nil {
-		panic(connectionError)
+		return nil, &Error{Op: OpDial, Err: connectionError}
+	}
+	return &websocketSource{conn: conn}, nil
+}
+
+func (w *websocketSource) ReadMessage() ([]byte, error) {
+	_, data, err := w.conn.ReadMessage()
+	if _, ok := err.(*websocket.CloseError); ok {
+		return nil, &Error{Op: OpUnexpectedClose, Err: err}
+	}
+	return data, err
+}
+
+func (w *websocketSource) WriteMessage(data []byte, deadline time.Time) error {
+	if err := w.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	return w.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Close sends a close frame to let the server know we are leaving before closing the connection
+func (w *websocketSource) Close() error {
+	closeMSG := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := w.conn.WriteControl(websocket.CloseMessage, closeMSG, time.Now().Add(closeFrameTimeout)); err != nil {
+		log.Debugf("failed to send close frame: %v\n", err)
 	}
-	return conn
+	return w.conn.Close()
 }
 
-func send(conn *websocket.Conn, msg interface{}) {
-	mux.Lock()
-	if err := conn.WriteJSON(msg); err != nil {
-		panic(err)
+// newSession starts the reader and writer of the new session, writes taking longer than writeTimeout fail the session.
+// The turnaround of the moves written is reported to latency, if not nil.
+func newSession(source MessageSource, writeTimeout time.Duration, recorder *Recorder, latency *latencyTracker) *session {
+	s := &session{
+		source:       source,
+		recorder:     recorder,
+		latency:      latency,
+		moveQueue:    make(chan interface{}, writeQueueSize),
+		normalQueue:  make(chan interface{}, writeQueueSize),
+		inbound:      make(chan inbound, readQueueSize),
+		done:         make(chan struct{}),
+		registeredCh: make(chan struct{}),
+		started:      time.Now(),
 	}
-	mux.Unlock()
+	go s.reader()
+	go s.writer(writeTimeout)
+	return s
+}
+
+// markRegistered is called when the server has accepted the player
+func (s *session) markRegistered() {
+	if !s.registered {
+		s.registered = true
+		close(s.registeredCh)
+	}
+}
+
+// progressed returns true if the session has received a map update or stayed up for stableSessionDuration,
+// a server that accepts the player and then drops the connection does not reset the reconnect attempts
+func (s *session) progressed() bool {
+	return s.playing || time.Since(s.started) >= stableSessionDuration
+}
+
+// fail closes the session because of a problem found outside the receive loop,
+// the first error given is returned by failure
+func (s *session) fail(err error) {
+	s.errMux.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.errMux.Unlock()
+	s.close()
+}
+
+func (s *session) failure() error {
+	s.errMux.Lock()
+	defer s.errMux.Unlock()
+	return s.err
+}
+
+// close closes the message source, it is safe to call close several times and concurrently with reads and writes
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		if err := s.source.Close(); err != nil {
+			log.Debugf("failed to close connection: %v\n", err)
+		}
+	})
 }
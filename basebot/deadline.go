@@ -0,0 +1,122 @@
+package basebot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/models"
+)
+
+// AnytimeMoveFunc calculates a move for a map update and can keep refining it until ctx is done.
+// Each call to propose replaces the previously proposed action, and the last one proposed
+// before the tick deadline, or before the function returns, is sent to the server.
+// Proposals made after the deadline are ignored.
+type AnytimeMoveFunc func(ctx context.Context, event models.MapUpdateEvent, propose func(action models.Action))
+
+const (
+	// used until the server has told us the tick length of the game
+	defaultTickDuration = 250 * time.Millisecond
+	// the bot always gets at least this long to think, even on a very slow connection
+	minMoveBudget = 10 * time.Millisecond
+)
+
+// setTickDuration updates the tick length from the settings the server is using for the game
+func (c *Client) setTickDuration(settings models.GameSettings) {
+	if settings.TimeInMSPerTick <= 0 {
+		return
+	}
+	c.mux.Lock()
+	c.tickDuration = time.Duration(settings.TimeInMSPerTick) * time.Millisecond
+	c.mux.Unlock()
+}
+
+// moveDeadline returns when a move for a map update received at the given time must be sent
+func (c *Client) moveDeadline(received time.Time) time.Time {
+	c.mux.Lock()
+	tick := c.tickDuration
+	c.mux.Unlock()
+	if tick == 0 {
+		tick = defaultTickDuration
+	}
+
+	budget := tick - c.latency.get().NetworkMargin() - c.MoveSafetyMargin
+	if budget < minMoveBudget {
+		budget = minMoveBudget
+	}
+	return received.Add(budget)
+}
+
+// calculateMoveBefore runs the bot and returns the last action it proposed before the deadline,
+// or FallbackAction if it did not propose anything in time.
+// The bot is never called concurrently, if the previous calculation is still running the fallback is used right away.
+// With WaitForBot the bot still gets the deadline in ctx, but the client waits for it to return and
+// takes its last proposal, however late.
+func (c *Client) calculateMoveBefore(ctx context.Context, deadline time.Time, event models.MapUpdateEvent) models.Action {
+	if c.WaitForBot {
+		select {
+		case c.calculating <- struct{}{}:
+		case <-ctx.Done():
+			return c.fallbackAction()
+		}
+	} else {
+		select {
+		case c.calculating <- struct{}{}:
+		default:
+			c.stats.update(func(stats *Stats) { stats.FallbackMoves++ })
+			log.Warnf("Still calculating an earlier move, using %s for tick %d\n", c.fallbackAction(), event.GameTick)
+			return c.fallbackAction()
+		}
+	}
+
+	// waiting for the bot is only interrupted when the client stops
+	wait := ctx.Done()
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	if !c.WaitForBot {
+		wait = ctx.Done()
+	}
+
+	var mux sync.Mutex
+	var proposed *models.Action
+	stopped := false
+	propose := func(action models.Action) {
+		mux.Lock()
+		defer mux.Unlock()
+		if !stopped && (c.WaitForBot || ctx.Err() == nil) {
+			proposed = &action
+		}
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		defer func() { <-c.calculating }()
+		defer close(finished)
+		c.Bot.OnMapUpdate(ctx, event, propose)
+	}()
+
+	select {
+	case <-finished:
+	case <-wait:
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+	stopped = true
+	cancel()
+	if proposed == nil {
+		c.stats.update(func(stats *Stats) { stats.FallbackMoves++ })
+		log.Warnf("No move for tick %d in time, using %s\n", event.GameTick, c.fallbackAction())
+		return c.fallbackAction()
+	}
+	return *proposed
+}
+
+func (c *Client) fallbackAction() models.Action {
+	if c.FallbackAction == "" {
+		return models.Stay
+	}
+	return c.FallbackAction
+}
@@ -0,0 +1,43 @@
+package basebot
+
+import (
+	"context"
+	"testing"
+
+	"paintbot-client/models"
+)
+
+func TestClient_lastProposalBeforeDeadlineIsSent(t *testing.T) {
+	client, source := replayClient(recording(1), AnytimeMoveFunc(func(ctx context.Context, event models.MapUpdateEvent, propose func(models.Action)) {
+		propose(models.Left)
+		propose(models.Right)
+		<-ctx.Done()
+		propose(models.Up)
+	}))
+
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	moves := source.moves()
+	if len(moves) != 1 || moves[0].Action != string(models.Right) {
+		t.Errorf("expected the last move proposed in time, got %+v", moves)
+	}
+	if stats := client.Stats(); stats.FallbackMoves != 0 {
+		t.Errorf("unexpected fallback moves %+v", stats)
+	}
+}
+
+func TestClient_lastProposalIsSentWhenBotReturns(t *testing.T) {
+	client, source := replayClient(recording(1), AnytimeMoveFunc(func(ctx context.Context, event models.MapUpdateEvent, propose func(models.Action)) {
+		propose(models.Left)
+		propose(models.Explode)
+	}))
+
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	moves := source.moves()
+	if len(moves) != 1 || moves[0].Action != string(models.Explode) {
+		t.Errorf("expected the last move proposed, got %+v", moves)
+	}
+}
@@ -0,0 +1,92 @@
+package basebot
+
+import (
+	"fmt"
+
+	"paintbot-client/models"
+)
+
+// Op describes the stage of the client in which an Error occurred
+type Op string
+
+const (
+	OpDial            Op = "dial"
+	OpRegister        Op = "register"
+	OpSend            Op = "send"
+	OpReceive         Op = "receive"
+	OpDecode          Op = "decode"
+	OpInvalidMessage  Op = "invalid message"
+	OpUnknownMessage  Op = "unknown message"
+	OpUnexpectedClose Op = "unexpected close"
+	OpCanceled        Op = "canceled"
+	OpHeartbeat       Op = "heartbeat"
+)
+
+// Error is returned when the client is unable to continue playing.
+// Op tells what the client was doing and Err holds the underlying cause.
+type Error struct {
+	Op  Op
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("paintbot: %s: %v", e.Op, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// InvalidMessageError holds the server's response to a message it could not handle
+type InvalidMessageError struct {
+	Message models.InvalidMessage
+	// Offending is the rejected message sent by the client, decoded from Message.ReceivedMessage.
+	// It is nil if the server's copy of the message could not be decoded.
+	Offending models.Message
+}
+
+func newInvalidMessageError(invalid models.InvalidMessage) *InvalidMessageError {
+	e := &InvalidMessageError{Message: invalid}
+	if offending, err := models.Decode([]byte(invalid.ReceivedMessage)); err == nil {
+		e.Offending = offending
+	}
+	return e
+}
+
+// Tick returns the game tick of the rejected message if it was a move
+func (e *InvalidMessageError) Tick() (tick int, ok bool) {
+	if move, ok := e.Offending.(models.RegisterMoveEvent); ok {
+		return move.GameTick, true
+	}
+	return 0, false
+}
+
+func (e *InvalidMessageError) Error() string {
+	if tick, ok := e.Tick(); ok {
+		return fmt.Sprintf("server rejected move for tick %d: %s", tick, e.Message.ErrorMessage)
+	}
+	if e.Offending != nil {
+		return fmt.Sprintf("server rejected %s: %s", e.Offending.MessageType(), e.Message.ErrorMessage)
+	}
+	return fmt.Sprintf("server rejected message: %s: %s", e.Message.ErrorMessage, e.Message.ReceivedMessage)
+}
+
+// InvalidMessagePolicy decides what the client does when the server rejects one of its messages
+type InvalidMessagePolicy int
+
+const (
+	// AbortOnInvalidMessage stops the client with an OpInvalidMessage error
+	AbortOnInvalidMessage InvalidMessagePolicy = iota
+	// ContinueOnInvalidMessage logs the error and keeps playing
+	ContinueOnInvalidMessage
+)
+
+// UnknownMessageError is used when the server sends a message type the client does not recognise
+type UnknownMessageError struct {
+	Type string
+	Raw  []byte
+}
+
+func (e *UnknownMessageError) Error() string {
+	return fmt.Sprintf("unknown message type %q: %s", e.Type, e.Raw)
+}
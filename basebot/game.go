@@ -0,0 +1,68 @@
+package basebot
+
+import (
+	"time"
+
+	"paintbot-client/models"
+)
+
+// GameInfo describes the game the client is currently playing
+type GameInfo struct {
+	GameID string
+	// Number counts the games played since the client was started, starting at 1
+	Number   int
+	Mode     models.GameMode
+	Width    int
+	Height   int
+	Settings models.GameSettings
+}
+
+// Game returns the game currently being played, or the last one if it has ended
+func (c *Client) Game() GameInfo {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.game
+}
+
+// beginGame resets all per game state when a new game starts
+func (c *Client) beginGame(event models.GameStartingEvent) GameInfo {
+	c.setTickDuration(event.GameSettings)
+
+	c.gameEnded = time.Time{}
+	c.mux.Lock()
+	c.game = GameInfo{
+		GameID:   event.GameID,
+		Number:   c.game.Number + 1,
+		Mode:     c.GameMode,
+		Width:    event.Width,
+		Height:   event.Height,
+		Settings: event.GameSettings,
+	}
+	game := c.game
+	c.mux.Unlock()
+
+	if h, ok := c.Bot.(NewGameHandler); ok {
+		h.OnNewGame(game)
+	}
+	return game
+}
+
+// doneAfterGame returns true if the client has nothing more to do once a game has ended.
+// A training session is a single game, while a tournament goes on until the TournamentEndedEvent,
+// or until the player is considered eliminated, see eliminationTimeout.
+// In an arena, or on a custom path, the client stays to play every game started until it is cancelled.
+// PlayerRank.Alive in a game result is not a sign of being out of a tournament, it only tells whether the
+// character was alive when that game ended.
+func (c *Client) doneAfterGame() bool {
+	return c.GameMode == models.Training
+}
+
+// eliminationTimeout returns a channel receiving when EliminationTimeout has passed since the last game ended
+// in a tournament, it is nil while a game is played or when the timeout is not used. stop releases the timer.
+func (c *Client) eliminationTimeout() (timeout <-chan time.Time, stop func()) {
+	if c.GameMode != models.Tournament || c.EliminationTimeout <= 0 || c.gameEnded.IsZero() {
+		return nil, func() {}
+	}
+	timer := time.NewTimer(c.EliminationTimeout - time.Since(c.gameEnded))
+	return timer.C, func() { timer.Stop() }
+}
@@ -0,0 +1,116 @@
+package basebot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"paintbot-client/models"
+)
+
+type gameRecorder struct {
+	MoveFunc
+	started []int
+	results int
+	ended   []string
+	done    bool
+}
+
+func (g *gameRecorder) OnNewGame(game GameInfo)                   { g.started = append(g.started, game.Number) }
+func (g *gameRecorder) OnGameResult(event models.GameResultEvent) { g.results++ }
+func (g *gameRecorder) OnGameEnded(event models.GameEndedEvent) {
+	g.ended = append(g.ended, event.GameID)
+}
+func (g *gameRecorder) OnTournamentEnded(event models.TournamentEndedEvent) { g.done = true }
+
+func newGameRecorder() *gameRecorder {
+	return &gameRecorder{MoveFunc: func(models.MapUpdateEvent) models.Action { return models.Stay }}
+}
+
+// a game result where the player's character is not alive
+const deadResult = `{"type":"se.cygni.paintbot.api.event.GameResultEvent","gameId":"g1",` +
+	`"playerRanks":[{"playerId":"p1","rank":2,"alive":false}],"receivingPlayerId":"p1"}`
+
+// withDeadResult adds deadResult before the game ended event, where the server sends the result
+func withDeadResult(stream string) string {
+	ended := `{"type":"se.cygni.paintbot.api.event.GameEndedEvent"`
+	return strings.Replace(stream, ended, deadResult+"\n"+ended, 1)
+}
+
+func TestClient_trainingEndsAfterGameEnded(t *testing.T) {
+	bot := newGameRecorder()
+	client, _ := replayClient(withDeadResult(recording(1)), bot)
+
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if bot.results != 1 || len(bot.ended) != 1 {
+		t.Errorf("expected result and end of the game, got %+v", bot)
+	}
+}
+
+func TestClient_tournamentPlaysUntilTournamentEnded(t *testing.T) {
+	bot := newGameRecorder()
+	second := strings.Replace(recording(2), `"g1"`, `"g2"`, -1)
+	// the second game without registration
+	second = second[strings.Index(second, "\n")+1:]
+	stream := strings.Join([]string{
+		withDeadResult(recording(1)),
+		second,
+		`{"type":"se.cygni.paintbot.api.event.TournamentEndedEvent","playerWinnerId":"p2","receivingPlayerId":"p1"}`,
+	}, "\n")
+	client, source := replayClient(stream, bot)
+	client.GameMode = models.Tournament
+
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(bot.started) != 2 || bot.started[1] != 2 {
+		t.Errorf("expected 2 games, got %v", bot.started)
+	}
+	if len(bot.ended) != 2 || bot.ended[0] != "g1" || bot.ended[1] != "g2" {
+		t.Errorf("expected both games to end, got %v", bot.ended)
+	}
+	if !bot.done || len(source.moves()) != 3 {
+		t.Errorf("expected tournament to end after 3 moves, got %+v and %d moves", bot, len(source.moves()))
+	}
+}
+
+func TestClient_tournamentEndsWhenNoNewGameStarts(t *testing.T) {
+	bot := newGameRecorder()
+	client := NewClient("test", models.Tournament, nil, bot)
+	client.Dial = func(ctx context.Context) (MessageSource, error) {
+		return newIdleSource(recording(1), true), nil
+	}
+	client.Reconnect.MaxAttempts = 0
+	client.EliminationTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < client.EliminationTimeout {
+		t.Errorf("left the tournament after %s, before the timeout", waited)
+	}
+	if len(bot.ended) != 1 || bot.done {
+		t.Errorf("expected one game and no end of the tournament, got %+v", bot)
+	}
+}
+
+func TestClient_tournamentWaitsForNextGameByDefault(t *testing.T) {
+	client := NewClient("test", models.Tournament, nil, newGameRecorder())
+	client.Dial = func(ctx context.Context) (MessageSource, error) {
+		return newIdleSource(recording(1), true), nil
+	}
+	client.Reconnect.MaxAttempts = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := client.Run(ctx)
+	var e *Error
+	if !errors.As(err, &e) || e.Op != OpCanceled {
+		t.Errorf("expected the client to wait until canceled, got %v", err)
+	}
+}
@@ -1,27 +1,74 @@
 package basebot
 
 import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
 	"time"
 
-	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
 
 	"paintbot-client/models"
 	"paintbot-client/utilities/timeHelper"
 )
 
-const timeBetweenHearbeats = 30 * time.Second
-
-func heartbeat(conn *websocket.Conn, playerID *string) {
+// heartbeat keeps the session alive once the player is registered, until the session is closed.
+// The session is failed if MaxMissedHeartbeats heartbeats in a row are left unanswered.
+func (c *Client) heartbeat(s *session) {
+	select {
+	case <-s.registeredCh:
+	case <-s.done:
+		return
+	}
 
 	for {
+		missed := atomic.LoadInt32(&s.unansweredHeartbeats)
+		if c.MaxMissedHeartbeats > 0 && int(missed) >= c.MaxMissedHeartbeats {
+			s.fail(&Error{Op: OpHeartbeat, Err: fmt.Errorf("no response to the last %d heartbeats", missed)})
+			return
+		}
+
 		rq := &models.HearbeatMessage{
-			Type:              "se.cygni.paintbot.api.request.HeartBeatRequest",
-			ReceivingPlayerID: playerID,
+			Type:              models.TypeHeartBeatRequest,
+			ReceivingPlayerID: c.PlayerID(),
 			Timestamp:         timeHelper.Now(),
 		}
 		log.Debug("sending hearbeat")
-		send(conn, rq)
-		time.Sleep(timeBetweenHearbeats)
+		c.latency.heartbeatRequested(time.Now())
+		atomic.AddInt32(&s.unansweredHeartbeats, 1)
+		if err := s.send(rq); err != nil {
+			log.Warnf("heartbeat failed: %v\n", err)
+		}
+
+		timer := time.NewTimer(c.heartbeatDelay())
+		select {
+		case <-s.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
 	}
 }
+
+// the largest HeartbeatJitter used, a jitter of 1 or more could leave no delay at all between heartbeats
+const maxHeartbeatJitter = 0.9
+
+// heartbeatDelay returns HeartbeatInterval randomly varied by HeartbeatJitter
+func (c *Client) heartbeatDelay() time.Duration {
+	interval := c.HeartbeatInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	jitter := c.HeartbeatJitter
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter > maxHeartbeatJitter {
+		jitter = maxHeartbeatJitter
+	}
+	return time.Duration(float64(interval) * (1 + (2*rand.Float64()-1)*jitter))
+}
+
+func (s *session) heartbeatResponded() {
+	atomic.StoreInt32(&s.unansweredHeartbeats, 0)
+}
@@ -0,0 +1,152 @@
+package basebot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"paintbot-client/models"
+)
+
+const registered = `{"type":"se.cygni.paintbot.api.response.PlayerRegistered","gameId":"g1","receivingPlayerId":"p1"}`
+
+// idleSource replays a recording and then waits for the connection to be closed,
+// heartbeats are only answered if answer is set
+type idleSource struct {
+	*FileSource
+	answer     bool
+	heartbeats int32
+}
+
+func newIdleSource(stream string, answer bool) *idleSource {
+	return &idleSource{FileSource: NewFileSource(strings.NewReader(stream)), answer: answer}
+}
+
+func (s *idleSource) ReadMessage() ([]byte, error) {
+	msg, err := s.FileSource.ReadMessage()
+	if err != io.EOF {
+		return msg, err
+	}
+	select {
+	case response := <-s.responses:
+		return response, nil
+	case <-s.closed:
+		return nil, errSessionClosed
+	}
+}
+
+func (s *idleSource) WriteMessage(data []byte, deadline time.Time) error {
+	if strings.Contains(string(data), models.TypeHeartBeatRequest) {
+		atomic.AddInt32(&s.heartbeats, 1)
+		if !s.answer {
+			return nil
+		}
+	}
+	return s.FileSource.WriteMessage(data, deadline)
+}
+
+func (s *idleSource) sentHeartbeats() int {
+	return int(atomic.LoadInt32(&s.heartbeats))
+}
+
+func heartbeatClient(bot Bot) *Client {
+	client := NewClient("test", models.Training, nil, bot)
+	client.HeartbeatInterval = 10 * time.Millisecond
+	client.HeartbeatJitter = 0
+	client.MaxMissedHeartbeats = 3
+	client.Reconnect = RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond, Multiplier: 1}
+	return client
+}
+
+func TestClient_missedHeartbeatsReconnect(t *testing.T) {
+	bot := &reconnectCounter{MoveFunc: func(models.MapUpdateEvent) models.Action { return models.Stay }}
+	client := heartbeatClient(bot)
+	var sources []*idleSource
+	client.Dial = func(ctx context.Context) (MessageSource, error) {
+		source := newIdleSource(registered, false)
+		sources = append(sources, source)
+		return source, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.Run(ctx)
+
+	var e *Error
+	if !errors.As(err, &e) || e.Op != OpHeartbeat {
+		t.Fatalf("expected heartbeat error, got %v", err)
+	}
+	if len(sources) != 2 || bot.reconnects != 1 {
+		t.Errorf("expected a reconnect after the missed heartbeats, got %d dials and %d reconnects", len(sources), bot.reconnects)
+	}
+	for i, source := range sources {
+		if heartbeats := source.sentHeartbeats(); heartbeats != client.MaxMissedHeartbeats {
+			t.Errorf("session %d: expected %d heartbeats, got %d", i+1, client.MaxMissedHeartbeats, heartbeats)
+		}
+	}
+}
+
+func TestClient_answeredHeartbeatsKeepConnection(t *testing.T) {
+	client := heartbeatClient(MoveFunc(func(models.MapUpdateEvent) models.Action { return models.Stay }))
+	source := newIdleSource(registered, true)
+	client.Dial = func(ctx context.Context) (MessageSource, error) { return source, nil }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := client.Run(ctx)
+
+	var e *Error
+	if !errors.As(err, &e) || e.Op != OpCanceled {
+		t.Fatalf("expected the client to play until canceled, got %v", err)
+	}
+	if heartbeats := source.sentHeartbeats(); heartbeats <= 2*client.MaxMissedHeartbeats {
+		t.Errorf("expected the responses to reset the missed heartbeats, only %d heartbeats sent", heartbeats)
+	}
+	if latency := client.Latency(); latency.RTTSamples == 0 {
+		t.Errorf("expected the responses to be measured, got %+v", latency)
+	}
+}
+
+func TestClient_heartbeatStopsWithSession(t *testing.T) {
+	client := heartbeatClient(nil)
+	client.HeartbeatInterval = time.Hour
+
+	for _, registered := range []bool{false, true} {
+		s := newSession(newIdleSource("", true), time.Second, nil, nil)
+		if registered {
+			s.markRegistered()
+		}
+		stopped := make(chan struct{})
+		go func() {
+			client.heartbeat(s)
+			close(stopped)
+		}()
+		s.close()
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Errorf("registered %t: heartbeat still running after the session closed", registered)
+		}
+	}
+}
+
+func TestClient_heartbeatDelay(t *testing.T) {
+	interval := 100 * time.Millisecond
+	for _, jitter := range []float64{-1, 0, 0.5, 1, 5} {
+		client := &Client{HeartbeatInterval: interval, HeartbeatJitter: jitter}
+		for i := 0; i < 100; i++ {
+			delay := client.heartbeatDelay()
+			if delay <= 0 || delay >= 2*interval {
+				t.Fatalf("jitter %v: delay %s out of range", jitter, delay)
+			}
+			if jitter <= 0 && delay != interval {
+				t.Fatalf("jitter %v: expected %s, got %s", jitter, interval, delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package basebot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// weight of a new sample in the moving averages
+const latencySmoothing = 0.125
+
+// LatencyStats describes the connection to the server as measured by the client
+type LatencyStats struct {
+	// RTT is the round trip time of the latest heartbeat, SmoothedRTT a moving average over all heartbeats
+	RTT         time.Duration
+	SmoothedRTT time.Duration
+	RTTSamples  int
+	// Turnaround is the time from a map update arriving until the move was written to the connection for the latest tick,
+	// SmoothedTurnaround a moving average over all ticks
+	Turnaround         time.Duration
+	SmoothedTurnaround time.Duration
+	// ClockSkew is how far the server's clock is ahead of ours, estimated from the heartbeat timestamps
+	ClockSkew time.Duration
+}
+
+// NetworkMargin is the time needed to get a map update to the client and its move back to the server
+func (s LatencyStats) NetworkMargin() time.Duration {
+	if s.RTT > s.SmoothedRTT {
+		return s.RTT
+	}
+	return s.SmoothedRTT
+}
+
+// ServerTime converts a timestamp in ms from the server's clock to the client's clock
+func (s LatencyStats) ServerTime(timestamp int) time.Time {
+	return time.Unix(0, int64(timestamp)*int64(time.Millisecond)).Add(-s.ClockSkew)
+}
+
+type latencyTracker struct {
+	mux           sync.Mutex
+	stats         LatencyStats
+	heartbeatSent time.Time
+}
+
+func (t *latencyTracker) heartbeatRequested(sent time.Time) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.heartbeatSent = sent
+}
+
+// heartbeatResponded pairs the response with the latest request, serverTimestamp is when the server answered
+func (t *latencyTracker) heartbeatResponded(received time.Time, serverTimestamp int) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.heartbeatSent.IsZero() {
+		return
+	}
+	rtt := received.Sub(t.heartbeatSent)
+	t.heartbeatSent = time.Time{}
+
+	// assume the server answered halfway through the round trip
+	midpoint := received.Add(-rtt / 2)
+	skew := time.Unix(0, int64(serverTimestamp)*int64(time.Millisecond)).Sub(midpoint)
+
+	s := &t.stats
+	s.RTT = rtt
+	if s.RTTSamples == 0 {
+		s.SmoothedRTT = rtt
+		s.ClockSkew = skew
+	} else {
+		s.SmoothedRTT = smooth(s.SmoothedRTT, rtt)
+		s.ClockSkew = smooth(s.ClockSkew, skew)
+	}
+	s.RTTSamples++
+}
+
+func (t *latencyTracker) moveSent(received, sent time.Time) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	turnaround := sent.Sub(received)
+	s := &t.stats
+	if s.Turnaround == 0 && s.SmoothedTurnaround == 0 {
+		s.SmoothedTurnaround = turnaround
+	} else {
+		s.SmoothedTurnaround = smooth(s.SmoothedTurnaround, turnaround)
+	}
+	s.Turnaround = turnaround
+}
+
+func (t *latencyTracker) get() LatencyStats {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.stats
+}
+
+func smooth(average, sample time.Duration) time.Duration {
+	return average + time.Duration(latencySmoothing*float64(sample-average))
+}
+
+type latencyKey struct{}
+
+// LatencyFromContext returns the latency measured when the map update was received,
+// ctx must be the context given to Bot.OnMapUpdate
+func LatencyFromContext(ctx context.Context) (LatencyStats, bool) {
+	stats, ok := ctx.Value(latencyKey{}).(LatencyStats)
+	return stats, ok
+}
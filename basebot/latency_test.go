@@ -0,0 +1,139 @@
+package basebot
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"paintbot-client/models"
+)
+
+func millis(t time.Time) int {
+	return int(t.UnixNano() / int64(time.Millisecond))
+}
+
+func TestLatencyTracker_heartbeatResponded(t *testing.T) {
+	start := time.Unix(1000, 0)
+	// each heartbeat is sent a second after the previous one, the server answers halfway through the round trip
+	cases := []struct {
+		rtt, skew                         time.Duration
+		expectedSmoothedRTT, expectedSkew time.Duration
+	}{
+		{100 * time.Millisecond, 2 * time.Second, 100 * time.Millisecond, 2 * time.Second},
+		{200 * time.Millisecond, time.Second, 112500 * time.Microsecond, 1875 * time.Millisecond},
+		{100 * time.Millisecond, 1875 * time.Millisecond, 110937500 * time.Nanosecond, 1875 * time.Millisecond},
+	}
+
+	var tracker latencyTracker
+	for i, tc := range cases {
+		sent := start.Add(time.Duration(i) * time.Second)
+		tracker.heartbeatRequested(sent)
+		tracker.heartbeatResponded(sent.Add(tc.rtt), millis(sent.Add(tc.rtt/2+tc.skew)))
+
+		stats := tracker.get()
+		if stats.RTT != tc.rtt || stats.SmoothedRTT != tc.expectedSmoothedRTT || stats.ClockSkew != tc.expectedSkew {
+			t.Errorf("heartbeat %d: expected RTT %s, smoothed %s and skew %s, got %+v",
+				i+1, tc.rtt, tc.expectedSmoothedRTT, tc.expectedSkew, stats)
+		}
+		if stats.RTTSamples != i+1 {
+			t.Errorf("heartbeat %d: expected %d samples, got %d", i+1, i+1, stats.RTTSamples)
+		}
+	}
+
+	// a response without a request cannot be measured
+	before := tracker.get()
+	tracker.heartbeatResponded(start.Add(time.Hour), millis(start))
+	if after := tracker.get(); after != before {
+		t.Errorf("unmatched response changed the stats from %+v to %+v", before, after)
+	}
+}
+
+func TestLatencyTracker_moveSent(t *testing.T) {
+	received := time.Unix(1000, 0)
+	var tracker latencyTracker
+	for _, tc := range []struct {
+		turnaround, expectedSmoothed time.Duration
+	}{
+		{80 * time.Millisecond, 80 * time.Millisecond},
+		{160 * time.Millisecond, 90 * time.Millisecond},
+	} {
+		tracker.moveSent(received, received.Add(tc.turnaround))
+		if stats := tracker.get(); stats.Turnaround != tc.turnaround || stats.SmoothedTurnaround != tc.expectedSmoothed {
+			t.Errorf("expected turnaround %s and smoothed %s, got %+v", tc.turnaround, tc.expectedSmoothed, stats)
+		}
+	}
+}
+
+func TestLatencyStats_NetworkMargin(t *testing.T) {
+	for _, tc := range []struct {
+		rtt, smoothed, expected time.Duration
+	}{
+		{0, 0, 0},
+		{50 * time.Millisecond, 30 * time.Millisecond, 50 * time.Millisecond},
+		{30 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond},
+	} {
+		stats := LatencyStats{RTT: tc.rtt, SmoothedRTT: tc.smoothed}
+		if margin := stats.NetworkMargin(); margin != tc.expected {
+			t.Errorf("%+v: expected %s, got %s", stats, tc.expected, margin)
+		}
+	}
+}
+
+func TestLatencyStats_ServerTime(t *testing.T) {
+	stats := LatencyStats{ClockSkew: 2 * time.Second}
+	local := time.Unix(1000, 0)
+	if converted := stats.ServerTime(millis(local.Add(2 * time.Second))); !converted.Equal(local) {
+		t.Errorf("expected %s, got %s", local, converted)
+	}
+}
+
+func TestLatencyFromContext(t *testing.T) {
+	if _, ok := LatencyFromContext(context.Background()); ok {
+		t.Error("expected no latency in a plain context")
+	}
+	stats := LatencyStats{RTT: time.Millisecond, RTTSamples: 1}
+	got, ok := LatencyFromContext(context.WithValue(context.Background(), latencyKey{}, stats))
+	if !ok || got != stats {
+		t.Errorf("expected %+v, got %+v", stats, got)
+	}
+}
+
+// slowSource takes delay to write every message
+type slowSource struct {
+	delay  time.Duration
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (s *slowSource) ReadMessage() ([]byte, error) {
+	<-s.closed
+	return nil, errSessionClosed
+}
+
+func (s *slowSource) WriteMessage(data []byte, deadline time.Time) error {
+	time.Sleep(s.delay)
+	return nil
+}
+
+func (s *slowSource) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+func TestSession_turnaroundIncludesWriting(t *testing.T) {
+	var tracker latencyTracker
+	s := newSession(&slowSource{delay: 50 * time.Millisecond, closed: make(chan struct{})}, time.Second, nil, &tracker)
+	defer s.close()
+
+	if err := s.sendMove(&models.RegisterMoveEvent{Type: models.TypeRegisterMove}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for tracker.get().Turnaround == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if turnaround := tracker.get().Turnaround; turnaround < 50*time.Millisecond {
+		t.Errorf("expected the write to be part of the turnaround, got %s", turnaround)
+	}
+}
@@ -0,0 +1,209 @@
+package basebot
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"paintbot-client/models"
+)
+
+// Environment variables read by ConnectionOptions.LoadEnv
+const (
+	EnvHost             = "PAINTBOT_HOST"
+	EnvPort             = "PAINTBOT_PORT"
+	EnvScheme           = "PAINTBOT_SCHEME"
+	EnvInsecure         = "PAINTBOT_INSECURE"
+	EnvHeaders          = "PAINTBOT_HEADERS"
+	EnvHandshakeTimeout = "PAINTBOT_HANDSHAKE_TIMEOUT"
+	EnvProxy            = "PAINTBOT_PROXY"
+)
+
+// ConnectionOptions describes where and how the client connects to the paintbot server
+type ConnectionOptions struct {
+	Host string
+	// Port is left out of the URL when 0, so the default port of the scheme is used
+	Port int
+	// Scheme is either "ws" or "wss"
+	Scheme           string
+	TLSConfig        *tls.Config
+	Header           http.Header
+	HandshakeTimeout time.Duration
+	// Proxy is used for the websocket handshake, nil means no proxy
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
+// DefaultConnectionOptions returns options for the public server hosted by Cygni
+func DefaultConnectionOptions() ConnectionOptions {
+	return ConnectionOptions{
+		Host:             "server.paintbot.cygni.se",
+		Scheme:           "ws",
+		Header:           http.Header{},
+		HandshakeTimeout: 45 * time.Second,
+		Proxy:            http.ProxyFromEnvironment,
+	}
+}
+
+// URL returns the websocket url for the given game mode
+func (o ConnectionOptions) URL(gameMode models.GameMode) url.URL {
+	host := o.Host
+	if o.Port != 0 {
+		host = net.JoinHostPort(o.Host, strconv.Itoa(o.Port))
+	}
+	return url.URL{
+		Scheme: o.Scheme,
+		Host:   host,
+		Path:   string(gameMode),
+	}
+}
+
+func (o ConnectionOptions) validate() error {
+	if o.Host == "" {
+		return fmt.Errorf("no host given")
+	}
+	if o.Scheme != "ws" && o.Scheme != "wss" {
+		return fmt.Errorf("unsupported scheme %q, expected ws or wss", o.Scheme)
+	}
+	return nil
+}
+
+// LoadEnv overrides the options with the PAINTBOT_* environment variables that are set.
+// PAINTBOT_HEADERS is a comma separated list of Key=Value pairs.
+func (o *ConnectionOptions) LoadEnv() error {
+	if v, ok := os.LookupEnv(EnvHost); ok {
+		o.Host = v
+	}
+	if v, ok := os.LookupEnv(EnvPort); ok {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s: %v", EnvPort, err)
+		}
+		o.Port = port
+	}
+	if v, ok := os.LookupEnv(EnvScheme); ok {
+		o.Scheme = v
+	}
+	if v, ok := os.LookupEnv(EnvInsecure); ok {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%s: %v", EnvInsecure, err)
+		}
+		o.setInsecure(insecure)
+	}
+	if v, ok := os.LookupEnv(EnvHeaders); ok {
+		for _, pair := range strings.Split(v, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			if err := (*headerFlag)(o).Set(pair); err != nil {
+				return fmt.Errorf("%s: %v", EnvHeaders, err)
+			}
+		}
+	}
+	if v, ok := os.LookupEnv(EnvHandshakeTimeout); ok {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%s: %v", EnvHandshakeTimeout, err)
+		}
+		o.HandshakeTimeout = timeout
+	}
+	if v, ok := os.LookupEnv(EnvProxy); ok {
+		if err := (*proxyFlag)(o).Set(v); err != nil {
+			return fmt.Errorf("%s: %v", EnvProxy, err)
+		}
+	}
+	return nil
+}
+
+// RegisterFlags adds command line flags for the options to fs, using the current values as defaults
+func (o *ConnectionOptions) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Host, "host", o.Host, "paintbot server host")
+	fs.IntVar(&o.Port, "port", o.Port, "paintbot server port, 0 for the default port of the scheme")
+	fs.StringVar(&o.Scheme, "scheme", o.Scheme, "websocket scheme, ws or wss")
+	fs.Var((*insecureFlag)(o), "insecure", "skip verification of the server's TLS certificate")
+	fs.Var((*headerFlag)(o), "header", "extra handshake header as Key=Value, can be repeated")
+	fs.DurationVar(&o.HandshakeTimeout, "handshake-timeout", o.HandshakeTimeout, "websocket handshake timeout")
+	fs.Var((*proxyFlag)(o), "proxy", "proxy url for the websocket connection, empty to connect directly")
+}
+
+func (o *ConnectionOptions) setInsecure(insecure bool) {
+	if o.TLSConfig == nil {
+		o.TLSConfig = &tls.Config{}
+	}
+	o.TLSConfig.InsecureSkipVerify = insecure
+}
+
+type insecureFlag ConnectionOptions
+
+func (f *insecureFlag) String() string {
+	if f == nil || f.TLSConfig == nil {
+		return "false"
+	}
+	return strconv.FormatBool(f.TLSConfig.InsecureSkipVerify)
+}
+
+func (f *insecureFlag) Set(v string) error {
+	insecure, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	(*ConnectionOptions)(f).setInsecure(insecure)
+	return nil
+}
+
+func (f *insecureFlag) IsBoolFlag() bool {
+	return true
+}
+
+type headerFlag ConnectionOptions
+
+func (f *headerFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	var pairs []string
+	for key, values := range f.Header {
+		for _, v := range values {
+			pairs = append(pairs, key+"="+v)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f *headerFlag) Set(v string) error {
+	parts := strings.SplitN(v, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("invalid header %q, expected Key=Value", v)
+	}
+	if f.Header == nil {
+		f.Header = http.Header{}
+	}
+	f.Header.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	return nil
+}
+
+type proxyFlag ConnectionOptions
+
+func (f *proxyFlag) String() string {
+	return ""
+}
+
+func (f *proxyFlag) Set(v string) error {
+	if v == "" {
+		f.Proxy = nil
+		return nil
+	}
+	proxyURL, err := url.Parse(v)
+	if err != nil {
+		return err
+	}
+	f.Proxy = http.ProxyURL(proxyURL)
+	return nil
+}
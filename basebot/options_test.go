@@ -0,0 +1,167 @@
+package basebot
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"paintbot-client/models"
+)
+
+func TestConnectionOptions_URL(t *testing.T) {
+	cases := []struct {
+		host   string
+		port   int
+		scheme string
+		url    string
+	}{
+		{"server.paintbot.cygni.se", 0, "ws", "ws://server.paintbot.cygni.se/training"},
+		{"server.paintbot.cygni.se", 0, "wss", "wss://server.paintbot.cygni.se/training"},
+		{"localhost", 8080, "ws", "ws://localhost:8080/training"},
+		{"::1", 8080, "ws", "ws://[::1]:8080/training"},
+	}
+	for _, c := range cases {
+		o := ConnectionOptions{Host: c.host, Port: c.port, Scheme: c.scheme}
+		if u := o.URL(models.Training); u.String() != c.url {
+			t.Errorf("expected %s, got %s", c.url, u.String())
+		}
+	}
+}
+
+func TestDefaultConnectionOptions_wss(t *testing.T) {
+	o := DefaultConnectionOptions()
+	o.Scheme = "wss"
+	if u := o.URL(models.Tournament); u.String() != "wss://server.paintbot.cygni.se/tournament" {
+		t.Errorf("expected the default wss port, got %s", u.String())
+	}
+	if err := o.validate(); err != nil {
+		t.Error(err)
+	}
+	o.Scheme = "http"
+	if err := o.validate(); err == nil {
+		t.Error("expected http to be rejected")
+	}
+}
+
+// setEnv sets the variables for the duration of a test
+func setEnv(t *testing.T, env map[string]string) func() {
+	for key, value := range env {
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return func() {
+		for key := range env {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestConnectionOptions_LoadEnv(t *testing.T) {
+	defer setEnv(t, map[string]string{
+		EnvHost:             "localhost",
+		EnvPort:             "8080",
+		EnvScheme:           "wss",
+		EnvInsecure:         "true",
+		EnvHeaders:          "Authorization=Bearer abc, X-Team = blue,",
+		EnvHandshakeTimeout: "3s",
+		EnvProxy:            "http://proxy:3128",
+	})()
+
+	o := DefaultConnectionOptions()
+	if err := o.LoadEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if o.Host != "localhost" || o.Port != 8080 || o.Scheme != "wss" || o.HandshakeTimeout != 3*time.Second {
+		t.Errorf("unexpected options %+v", o)
+	}
+	if o.TLSConfig == nil || !o.TLSConfig.InsecureSkipVerify {
+		t.Error("expected insecure TLS")
+	}
+	if o.Header.Get("Authorization") != "Bearer abc" || o.Header.Get("X-Team") != "blue" {
+		t.Errorf("unexpected headers %v", o.Header)
+	}
+	proxy, err := o.Proxy(&http.Request{})
+	if err != nil || proxy == nil || proxy.Host != "proxy:3128" {
+		t.Errorf("unexpected proxy %v (%v)", proxy, err)
+	}
+}
+
+func TestConnectionOptions_LoadEnv_invalid(t *testing.T) {
+	cases := map[string]string{
+		EnvPort:             "eighty",
+		EnvInsecure:         "maybe",
+		EnvHeaders:          "NoValue",
+		EnvHandshakeTimeout: "3",
+		EnvProxy:            "http://[::1",
+	}
+	for key, value := range cases {
+		restore := setEnv(t, map[string]string{key: value})
+		o := DefaultConnectionOptions()
+		if err := o.LoadEnv(); err == nil {
+			t.Errorf("%s=%s should fail", key, value)
+		}
+		restore()
+	}
+
+	// nothing set changes nothing
+	o := DefaultConnectionOptions()
+	if err := o.LoadEnv(); err != nil || o.Host != DefaultConnectionOptions().Host || o.Port != 0 {
+		t.Errorf("unexpected options %+v (%v)", o, err)
+	}
+}
+
+func TestConnectionOptions_RegisterFlags(t *testing.T) {
+	cases := []struct {
+		args  []string
+		check func(o ConnectionOptions) bool
+	}{
+		{[]string{"-host", "localhost", "-port", "8080"}, func(o ConnectionOptions) bool {
+			return o.Host == "localhost" && o.Port == 8080 && o.Scheme == "ws"
+		}},
+		{[]string{"-scheme", "wss"}, func(o ConnectionOptions) bool {
+			u := o.URL(models.Training)
+			return u.String() == "wss://server.paintbot.cygni.se/training"
+		}},
+		{[]string{"-insecure"}, func(o ConnectionOptions) bool {
+			return o.TLSConfig != nil && o.TLSConfig.InsecureSkipVerify
+		}},
+		{[]string{"-insecure=false"}, func(o ConnectionOptions) bool {
+			return o.TLSConfig == nil || !o.TLSConfig.InsecureSkipVerify
+		}},
+		{[]string{"-header", "A=1", "-header", "A=2", "-header", "B=x=y"}, func(o ConnectionOptions) bool {
+			return len(o.Header["A"]) == 2 && o.Header.Get("B") == "x=y"
+		}},
+		{[]string{"-handshake-timeout", "1m"}, func(o ConnectionOptions) bool {
+			return o.HandshakeTimeout == time.Minute
+		}},
+		{[]string{"-proxy", ""}, func(o ConnectionOptions) bool {
+			return o.Proxy == nil
+		}},
+		{[]string{"-proxy", "http://proxy:3128"}, func(o ConnectionOptions) bool {
+			proxy, err := o.Proxy(&http.Request{})
+			return err == nil && proxy.Host == "proxy:3128"
+		}},
+	}
+	for _, c := range cases {
+		o := DefaultConnectionOptions()
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		o.RegisterFlags(fs)
+		if err := fs.Parse(c.args); err != nil || !c.check(o) {
+			t.Errorf("%v: unexpected options %+v (%v)", c.args, o, err)
+		}
+	}
+
+	for _, args := range [][]string{{"-header", "=1"}, {"-insecure=maybe"}, {"-port", "x"}} {
+		o := DefaultConnectionOptions()
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		o.RegisterFlags(fs)
+		if err := fs.Parse(args); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
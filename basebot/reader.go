@@ -0,0 +1,104 @@
+package basebot
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/models"
+)
+
+// number of received messages that can wait for the client loop
+const readQueueSize = 64
+
+// inbound is a message read from the server, or the error that stopped the reader
+type inbound struct {
+	raw       []byte
+	msg       models.Message
+	decodeErr error
+	received  time.Time
+	err       error
+}
+
+// reader reads and decodes messages from the connection until it fails or the session is closed,
+// so that the client loop can see which map updates are already waiting while the bot is thinking
+func (s *session) reader() {
+	defer close(s.inbound)
+	for {
+		raw, err := s.source.ReadMessage()
+		if err != nil {
+			if _, ok := err.(*Error); !ok {
+				err = &Error{Op: OpReceive, Err: err}
+			}
+			select {
+			case s.inbound <- inbound{err: err}:
+			case <-s.done:
+			}
+			return
+		}
+
+		in := inbound{raw: raw, received: time.Now()}
+		log.Debugf("Received: %s\n", raw)
+		s.recorder.Record(Inbound, raw)
+		in.msg, in.decodeErr = models.Decode(raw)
+		if update, ok := in.msg.(models.MapUpdateEvent); ok {
+			s.latestMux.Lock()
+			s.latestGameID, s.latestTick = update.GameID, update.GameTick
+			s.latestMux.Unlock()
+		}
+
+		select {
+		case s.inbound <- in:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// nextBatch blocks until a message is received and returns it together with all other messages already waiting.
+// It returns nil when the reader has stopped, and timedOut if timeout fires before any message is received.
+func (s *session) nextBatch(timeout <-chan time.Time) (batch []inbound, timedOut bool) {
+	var in inbound
+	var ok bool
+	select {
+	case in, ok = <-s.inbound:
+	case <-timeout:
+		return nil, true
+	}
+	if !ok {
+		return nil, false
+	}
+	batch = []inbound{in}
+	for {
+		select {
+		case in, ok := <-s.inbound:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, in)
+		default:
+			return batch, false
+		}
+	}
+}
+
+// isStale returns true if a newer map update than the given tick has been received for the game
+func (s *session) isStale(gameID string, tick int) bool {
+	s.latestMux.Lock()
+	defer s.latestMux.Unlock()
+	return s.latestGameID == gameID && s.latestTick > tick
+}
+
+// supersededUpdate returns true if batch[i] is a map update and a later one for the same game is waiting after it
+func supersededUpdate(batch []inbound, i int) bool {
+	update, ok := batch[i].msg.(models.MapUpdateEvent)
+	if !ok {
+		return false
+	}
+	for _, later := range batch[i+1:] {
+		if next, ok := later.msg.(models.MapUpdateEvent); ok && next.GameID == update.GameID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package basebot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"paintbot-client/models"
+)
+
+// gatedSource returns its messages in order, holding back everything after the first map update until
+// release is closed. drained is closed when all but the last message have been read, and the last one
+// is held back until a move has been written.
+type gatedSource struct {
+	messages []string
+	next     int
+	release  chan struct{}
+	drained  chan struct{}
+	moved    chan struct{}
+	closed   chan struct{}
+	once     sync.Once
+
+	mux   sync.Mutex
+	moves []int
+}
+
+func newGatedSource(messages ...string) *gatedSource {
+	return &gatedSource{
+		messages: messages,
+		release:  make(chan struct{}),
+		drained:  make(chan struct{}),
+		moved:    make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (g *gatedSource) ReadMessage() ([]byte, error) {
+	if g.next == 3 {
+		<-g.release
+	}
+	if g.next == len(g.messages)-1 {
+		<-g.moved
+	}
+	if g.next == len(g.messages) {
+		<-g.closed
+		return nil, errors.New("closed")
+	}
+	msg := g.messages[g.next]
+	g.next++
+	if g.next == len(g.messages)-1 {
+		close(g.drained)
+	}
+	return []byte(msg), nil
+}
+
+func (g *gatedSource) WriteMessage(data []byte, deadline time.Time) error {
+	if msg, err := models.Decode(data); err == nil {
+		if move, ok := msg.(models.RegisterMoveEvent); ok {
+			g.mux.Lock()
+			g.moves = append(g.moves, move.GameTick)
+			g.mux.Unlock()
+			select {
+			case g.moved <- struct{}{}:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+func (g *gatedSource) Close() error {
+	g.once.Do(func() { close(g.closed) })
+	return nil
+}
+
+func mapUpdate(gameID string, tick int) string {
+	return fmt.Sprintf(`{"type":"se.cygni.paintbot.api.event.MapUpdateEvent","gameId":"%s","gameTick":%d,`+
+		`"map":{"width":3,"height":3,"characterInfos":[{"id":"p1","position":4}]},"receivingPlayerId":"p1"}`, gameID, tick)
+}
+
+// map updates arriving while the bot is thinking make its move stale, and all but the newest are skipped
+func TestClient_skipsQueuedMapUpdates(t *testing.T) {
+	source := newGatedSource(
+		`{"type":"se.cygni.paintbot.api.response.PlayerRegistered","gameId":"g1","receivingPlayerId":"p1","gameSettings":{"timeInMsPerTick":1000}}`,
+		`{"type":"se.cygni.paintbot.api.event.GameStartingEvent","gameId":"g1","width":3,"height":3,"receivingPlayerId":"p1"}`,
+		mapUpdate("g1", 0),
+		mapUpdate("g1", 1),
+		mapUpdate("g1", 2),
+		mapUpdate("g1", 3),
+		`{"type":"se.cygni.paintbot.api.event.GameEndedEvent","gameId":"g1","receivingPlayerId":"p1"}`,
+	)
+
+	var ticks []int
+	client := NewClient("test", models.Training, nil, MoveFunc(func(event models.MapUpdateEvent) models.Action {
+		ticks = append(ticks, event.GameTick)
+		if event.GameTick == 0 {
+			close(source.release)
+			<-source.drained
+			// let the reader hand over the last messages
+			time.Sleep(50 * time.Millisecond)
+		}
+		return models.Left
+	}))
+	client.Dial = func(ctx context.Context) (MessageSource, error) { return source, nil }
+	client.Reconnect.MaxAttempts = 0
+
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ticks, []int{0, 3}) {
+		t.Errorf("expected the bot to get ticks 0 and 3, got %v", ticks)
+	}
+	if !reflect.DeepEqual(source.moves, []int{3}) {
+		t.Errorf("expected only a move for tick 3, got %v", source.moves)
+	}
+	expected := Stats{MapUpdates: 4, MovesSent: 1, SkippedTicks: 2, StaleMoves: 1}
+	if stats := client.Stats(); stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestSupersededUpdate(t *testing.T) {
+	update := func(gameID string, tick int) inbound {
+		msg, _ := models.Decode([]byte(mapUpdate(gameID, tick)))
+		return inbound{msg: msg}
+	}
+	other := inbound{msg: models.GameEndedEvent{Type: models.TypeGameEnded}}
+
+	batch := []inbound{update("g1", 1), other, update("g1", 2), update("g2", 0)}
+	expected := []bool{true, false, false, false}
+	for i := range batch {
+		if supersededUpdate(batch, i) != expected[i] {
+			t.Errorf("%d: expected %t", i, expected[i])
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package basebot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/models"
+)
+
+// Direction tells if a recorded message was received or sent by the client
+type Direction string
+
+const (
+	Inbound  Direction = "in"
+	Outbound Direction = "out"
+)
+
+// RecordedMessage is one line in a recording
+type RecordedMessage struct {
+	// ElapsedNS is the time since the recording started in nanoseconds, from the monotonic clock
+	ElapsedNS int64           `json:"elapsedNs"`
+	Direction Direction       `json:"direction"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// longest line accepted in a recording, map updates for big maps can be large
+const maxRecordedMessageSize = 16 * 1024 * 1024
+
+// RecordingReader reads the server messages of a recording, one JSON message per line.
+// Recordings written by a Recorder are read as well, then only the received messages are returned.
+type RecordingReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewRecordingReader reads the recording in r
+func NewRecordingReader(r io.Reader) *RecordingReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordedMessageSize)
+	return &RecordingReader{scanner: scanner}
+}
+
+// Next returns the next server message, or io.EOF at the end of the recording
+func (r *RecordingReader) Next() ([]byte, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		msg := make([]byte, len(line))
+		copy(msg, line)
+
+		// a Recorder line has a direction where a server message has a type
+		header := struct {
+			models.GameMessage
+			RecordedMessage
+		}{}
+		if err := json.Unmarshal(msg, &header); err == nil && header.Type == "" && header.Direction != "" {
+			if header.Direction != Inbound {
+				continue
+			}
+			msg = header.Message
+		}
+		return msg, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Line returns the line number of the message last returned by Next, starting at 1
+func (r *RecordingReader) Line() int {
+	return r.line
+}
+
+// Recorder writes every message received and sent by a client as JSON Lines, one file per game named by the game id.
+// Messages seen before the first game id, such as the registration, are written to the first game's file.
+type Recorder struct {
+	dir   string
+	start time.Time
+
+	mux     sync.Mutex
+	gameID  string
+	file    *os.File
+	writer  *bufio.Writer
+	pending []RecordedMessage
+	failed  bool
+}
+
+// NewRecorder creates a recorder writing to dir, which is created if needed
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, start: time.Now()}, nil
+}
+
+// Record adds a message to the recording of the game it belongs to
+func (r *Recorder) Record(direction Direction, msg []byte) {
+	if r == nil {
+		return
+	}
+	entry := RecordedMessage{
+		ElapsedNS: int64(time.Since(r.start)),
+		Direction: direction,
+		Message:   append(json.RawMessage(nil), msg...),
+	}
+
+	header := struct {
+		GameID string `json:"gameId"`
+	}{}
+	_ = json.Unmarshal(msg, &header)
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.failed {
+		return
+	}
+	if header.GameID != "" && header.GameID != r.gameID {
+		if err := r.switchGame(header.GameID); err != nil {
+			log.Errorf("Recording stopped: %v\n", err)
+			r.failed = true
+			return
+		}
+	}
+	if r.writer == nil {
+		r.pending = append(r.pending, entry)
+		return
+	}
+	if err := r.write(entry); err != nil {
+		log.Errorf("Recording stopped: %v\n", err)
+		r.failed = true
+	}
+}
+
+// Close flushes the current recording, messages not belonging to any game are written to a file named by the start time
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if len(r.pending) > 0 && !r.failed {
+		if err := r.switchGame(fmt.Sprintf("nogame-%s", r.start.Format("20060102-150405"))); err != nil {
+			return err
+		}
+	}
+	return r.closeFile()
+}
+
+func (r *Recorder) switchGame(gameID string) error {
+	if err := r.closeFile(); err != nil {
+		return err
+	}
+	path := filepath.Join(r.dir, recordingFileName(gameID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	log.Infof("Recording game to %s\n", path)
+	r.gameID = gameID
+	r.file = file
+	r.writer = bufio.NewWriter(file)
+
+	pending := r.pending
+	r.pending = nil
+	for _, entry := range pending {
+		if err := r.write(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) write(entry RecordedMessage) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := r.writer.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return r.writer.Flush()
+}
+
+func (r *Recorder) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	flushErr := r.writer.Flush()
+	closeErr := r.file.Close()
+	r.file, r.writer = nil, nil
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+// recordingFileName returns the file name for a game. The id comes from the server, so anything but letters,
+// digits, '-' and '_' is replaced to keep the file inside the recording directory, and a hash of the id is added
+// to keep escaped ids apart.
+func recordingFileName(gameID string) string {
+	escaped := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, gameID)
+	if escaped != gameID || escaped == "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(gameID))
+		escaped = fmt.Sprintf("%s-%08x", escaped, h.Sum32())
+	}
+	return escaped + ".jsonl"
+}
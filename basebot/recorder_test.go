@@ -0,0 +1,79 @@
+package basebot
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordingFileName(t *testing.T) {
+	if name := recordingFileName("a1b2-c3_d4"); name != "a1b2-c3_d4.jsonl" {
+		t.Errorf("expected game id to be kept, got %s", name)
+	}
+	for _, id := range []string{"../../x", "..", "a/b", `a\b`, ""} {
+		name := recordingFileName(id)
+		if filepath.Base(name) != name || name == "..jsonl" || filepath.Ext(name) != ".jsonl" {
+			t.Errorf("%q: unsafe file name %q", id, name)
+		}
+	}
+	if recordingFileName("a/b") == recordingFileName("a_b") {
+		t.Error("escaped id should not collide with a plain one")
+	}
+}
+
+func TestRecorder_gameIDCannotLeaveDirectory(t *testing.T) {
+	parent, err := ioutil.TempDir("", "paintbot-recording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+	dir := filepath.Join(parent, "recordings")
+
+	r, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Record(Inbound, []byte(`{"type":"x","gameId":"../escaped"}`))
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(parent, "escaped.jsonl")); !os.IsNotExist(err) {
+		t.Error("recording written outside the directory")
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("expected one recording in the directory, got %d", len(files))
+	}
+}
+
+func TestRecordingReader(t *testing.T) {
+	r := NewRecordingReader(strings.NewReader(`{"type":"a"}
+
+{"elapsedNs":1,"direction":"out","message":{"type":"b"}}
+{"elapsedNs":2,"direction":"in","message":{"type":"c"}}
+`))
+
+	expected := []struct {
+		msg  string
+		line int
+	}{
+		{`{"type":"a"}`, 1},
+		{`{"type":"c"}`, 4},
+	}
+	for _, e := range expected {
+		msg, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg) != e.msg || r.Line() != e.line {
+			t.Errorf("expected %s on line %d, got %s on line %d", e.msg, e.line, msg, r.Line())
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF at the end, got %v", err)
+	}
+}
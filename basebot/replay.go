@@ -0,0 +1,145 @@
+package basebot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"paintbot-client/models"
+	"paintbot-client/utilities/timeHelper"
+)
+
+// FileSource is a MessageSource replaying recorded server messages, read with a RecordingReader.
+// Messages written by the client are discarded, but heartbeats are answered so the connection is never
+// considered dead. After each map update the next message is held back until the client has sent its move.
+// Together with Client.WaitForBot, as set by Replay, every recorded tick reaches the bot no matter how long it thinks.
+type FileSource struct {
+	recording    *RecordingReader
+	closer       io.Closer
+	moved        chan struct{}
+	responses    chan []byte
+	closed       chan struct{}
+	closeOnce    sync.Once
+	awaitingMove bool
+}
+
+// NewFileSource replays the messages read from r
+func NewFileSource(r io.Reader) *FileSource {
+	f := &FileSource{
+		recording: NewRecordingReader(r),
+		moved:     make(chan struct{}, 1),
+		responses: make(chan []byte, writeQueueSize),
+		closed:    make(chan struct{}),
+	}
+	if closer, ok := r.(io.Closer); ok {
+		f.closer = closer
+	}
+	return f
+}
+
+// OpenFileSource replays the messages recorded in the file at path
+func OpenFileSource(path string) (*FileSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewFileSource(file), nil
+}
+
+// ReadMessage returns the next recorded message, or io.EOF at the end of the recording
+func (f *FileSource) ReadMessage() ([]byte, error) {
+	if f.awaitingMove {
+		for f.awaitingMove {
+			select {
+			case response := <-f.responses:
+				return response, nil
+			case <-f.moved:
+				f.awaitingMove = false
+			case <-f.closed:
+				return nil, errSessionClosed
+			}
+		}
+	} else {
+		select {
+		case response := <-f.responses:
+			return response, nil
+		case <-f.closed:
+			return nil, errSessionClosed
+		default:
+		}
+	}
+
+	msg, err := f.recording.Next()
+	if err != nil {
+		return nil, err
+	}
+	header := models.GameMessage{}
+	if err := json.Unmarshal(msg, &header); err == nil && header.Type == models.TypeMapUpdate {
+		f.awaitingMove = true
+	}
+	return msg, nil
+}
+
+// WriteMessage discards msg, answering heartbeats and releasing the next tick after a move
+func (f *FileSource) WriteMessage(data []byte, deadline time.Time) error {
+	select {
+	case <-f.closed:
+		return errSessionClosed
+	default:
+	}
+
+	header := models.GameMessage{}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+	switch header.Type {
+	case models.TypeRegisterMove:
+		select {
+		case f.moved <- struct{}{}:
+		default:
+		}
+	case models.TypeHeartBeatRequest:
+		response, err := json.Marshal(models.HeartBeatResponse{
+			Type:              models.TypeHeartBeatResponse,
+			ReceivingPlayerID: header.ReceivingPlayerID,
+			Timestamp:         timeHelper.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		select {
+		case f.responses <- response:
+		default:
+			return errors.New("too many unanswered heartbeats")
+		}
+	}
+	return nil
+}
+
+func (f *FileSource) Close() error {
+	var err error
+	f.closeOnce.Do(func() {
+		close(f.closed)
+		if f.closer != nil {
+			err = f.closer.Close()
+		}
+	})
+	return err
+}
+
+// Replay plays bot against a recording of server messages instead of a live server, see FileSource.
+// The client waits for the bot on every tick, so the replay does not depend on how fast the bot is.
+// An error with Op OpReceive wrapping io.EOF is returned if the recording ends before the game does.
+func Replay(ctx context.Context, path string, playerName string, gameMode models.GameMode, bot Bot) error {
+	client := NewClient(playerName, gameMode, nil, bot)
+	client.Dial = func(ctx context.Context) (MessageSource, error) {
+		return OpenFileSource(path)
+	}
+	client.Reconnect.MaxAttempts = 0
+	client.WaitForBot = true
+	return client.Run(ctx)
+}
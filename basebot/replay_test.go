@@ -0,0 +1,167 @@
+package basebot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"paintbot-client/models"
+)
+
+// capturingSource replays a recording and keeps everything written by the client
+type capturingSource struct {
+	*FileSource
+	mux     sync.Mutex
+	written []models.Message
+}
+
+func (s *capturingSource) WriteMessage(data []byte, deadline time.Time) error {
+	if msg, err := models.Decode(data); err == nil {
+		s.mux.Lock()
+		s.written = append(s.written, msg)
+		s.mux.Unlock()
+	}
+	return s.FileSource.WriteMessage(data, deadline)
+}
+
+func (s *capturingSource) moves() []models.RegisterMoveEvent {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	var moves []models.RegisterMoveEvent
+	for _, msg := range s.written {
+		if move, ok := msg.(models.RegisterMoveEvent); ok {
+			moves = append(moves, move)
+		}
+	}
+	return moves
+}
+
+func recording(ticks int, extra ...string) string {
+	lines := []string{
+		`{"type":"se.cygni.paintbot.api.response.PlayerRegistered","gameId":"g1","receivingPlayerId":"p1","gameSettings":{"timeInMsPerTick":200}}`,
+		`{"type":"se.cygni.paintbot.api.event.GameStartingEvent","gameId":"g1","width":3,"height":3,"receivingPlayerId":"p1"}`,
+	}
+	lines = append(lines, extra...)
+	for tick := 0; tick < ticks; tick++ {
+		lines = append(lines, fmt.Sprintf(`{"type":"se.cygni.paintbot.api.event.MapUpdateEvent","gameId":"g1","gameTick":%d,`+
+			`"map":{"width":3,"height":3,"characterInfos":[{"id":"p1","position":4}]},"receivingPlayerId":"p1"}`, tick))
+	}
+	lines = append(lines, `{"type":"se.cygni.paintbot.api.event.GameEndedEvent","gameId":"g1","playerWinnerId":"p1","receivingPlayerId":"p1"}`)
+	return strings.Join(lines, "\n")
+}
+
+func replayClient(stream string, bot Bot) (*Client, *capturingSource) {
+	source := &capturingSource{FileSource: NewFileSource(strings.NewReader(stream))}
+	client := NewClient("test", models.Training, nil, bot)
+	client.Dial = func(ctx context.Context) (MessageSource, error) {
+		return source, nil
+	}
+	client.Reconnect.MaxAttempts = 0
+	return client, source
+}
+
+func TestClient_replaysEveryTick(t *testing.T) {
+	client, source := replayClient(recording(5), MoveFunc(func(event models.MapUpdateEvent) models.Action {
+		return models.Left
+	}))
+
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	moves := source.moves()
+	if len(moves) != 5 {
+		t.Fatalf("expected 5 moves, got %d", len(moves))
+	}
+	for i, move := range moves {
+		if move.GameTick != i || move.Action != string(models.Left) || move.GameID != "g1" {
+			t.Errorf("unexpected move %+v", move)
+		}
+	}
+	if stats := client.Stats(); stats.MovesSent != 5 || stats.MapUpdates != 5 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if game := client.Game(); game.Number != 1 || game.GameID != "g1" || game.Width != 3 {
+		t.Errorf("unexpected game %+v", game)
+	}
+}
+
+func TestRecorder_recordingCanBeReplayed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "paintbot-recording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bot := MoveFunc(func(event models.MapUpdateEvent) models.Action { return models.Right })
+	client, _ := replayClient(recording(3), bot)
+	if client.Recorder, err = NewRecorder(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "g1.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	directions := map[Direction]int{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		entry := RecordedMessage{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		directions[entry.Direction]++
+	}
+	// at least registration, game starting, 3 map updates and game ended in,
+	// and register, client info, start game and 3 moves out, besides heartbeats
+	if directions[Inbound] < 6 || directions[Outbound] < 6 {
+		t.Errorf("unexpected recording: %v", directions)
+	}
+
+	client, source := replayClient(string(data), bot)
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if moves := source.moves(); len(moves) != 3 {
+		t.Errorf("expected 3 moves from the replayed recording, got %d", len(moves))
+	}
+}
+
+func TestClient_waitForBotReplaysEveryTickToSlowBot(t *testing.T) {
+	calls := 0
+	client, source := replayClient(recording(3), MoveFunc(func(event models.MapUpdateEvent) models.Action {
+		calls++
+		// longer than the 200ms ticks of the recording
+		time.Sleep(300 * time.Millisecond)
+		return models.Up
+	}))
+	client.WaitForBot = true
+
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	moves := source.moves()
+	if calls != 3 || len(moves) != 3 {
+		t.Fatalf("expected the bot to move in all 3 ticks, called %d times, %d moves", calls, len(moves))
+	}
+	for _, move := range moves {
+		if move.Action != string(models.Up) {
+			t.Errorf("unexpected move %+v", move)
+		}
+	}
+	if stats := client.Stats(); stats.FallbackMoves != 0 {
+		t.Errorf("unexpected fallback moves %+v", stats)
+	}
+}
@@ -0,0 +1,67 @@
+package basebot
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy controls how the client reconnects after the connection to the server is lost
+type RetryPolicy struct {
+	// MaxAttempts is the number of reconnects in a row before giving up, 0 disables reconnecting.
+	// The count starts over once a session receives a map update or stays up for a minute.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Multiplier is applied to the backoff after each failed attempt
+	Multiplier float64
+}
+
+// DefaultRetryPolicy returns the policy used by NewClient
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+	}
+}
+
+// Backoff returns how long to wait before the given attempt, starting at 1
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		backoff *= p.Multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(backoff)
+}
+
+// returns true if err means the connection is gone and a reconnect might help
+func isConnectionError(err error) bool {
+	e, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	switch e.Op {
+	case OpDial, OpRegister, OpSend, OpReceive, OpUnexpectedClose, OpHeartbeat:
+		return true
+	}
+	return false
+}
+
+// sleep waits for d or until ctx is done, returning false if ctx is done
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
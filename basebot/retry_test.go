@@ -0,0 +1,100 @@
+package basebot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"paintbot-client/models"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		800 * time.Millisecond, time.Second, time.Second}
+	for i, e := range expected {
+		if backoff := p.Backoff(i + 1); backoff != e {
+			t.Errorf("attempt %d: expected %s, got %s", i+1, e, backoff)
+		}
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	cases := map[error]bool{
+		&Error{Op: OpDial, Err: io.EOF}:            true,
+		&Error{Op: OpReceive, Err: io.EOF}:         true,
+		&Error{Op: OpUnexpectedClose, Err: io.EOF}: true,
+		&Error{Op: OpHeartbeat, Err: io.EOF}:       true,
+		&Error{Op: OpDecode, Err: io.EOF}:          false,
+		&Error{Op: OpInvalidMessage, Err: io.EOF}:  false,
+		&Error{Op: OpCanceled, Err: io.EOF}:        false,
+		errors.New("other"):                        false,
+	}
+	for err, expected := range cases {
+		if isConnectionError(err) != expected {
+			t.Errorf("%v: expected %t", err, expected)
+		}
+	}
+}
+
+type reconnectCounter struct {
+	MoveFunc
+	reconnects int
+}
+
+func (r *reconnectCounter) OnReconnect(attempt int, cause error) {
+	r.reconnects++
+}
+
+// a server accepting the player and then dropping the connection must not be reconnected to forever
+func TestClient_Run_givesUpWhenRegisteredSessionsDrop(t *testing.T) {
+	registeredOnly := `{"type":"se.cygni.paintbot.api.response.PlayerRegistered","gameId":"g1","receivingPlayerId":"p1"}`
+	dials := 0
+	bot := &reconnectCounter{MoveFunc: func(models.MapUpdateEvent) models.Action { return models.Stay }}
+	client := NewClient("test", models.Training, nil, bot)
+	client.Dial = func(ctx context.Context) (MessageSource, error) {
+		dials++
+		return NewFileSource(strings.NewReader(registeredOnly)), nil
+	}
+	client.Reconnect = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 1}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.Run(ctx)
+
+	var e *Error
+	if !errors.As(err, &e) || e.Op != OpReceive || !errors.Is(err, io.EOF) {
+		t.Errorf("expected receive error wrapping EOF, got %v", err)
+	}
+	if dials != 4 || bot.reconnects != 3 {
+		t.Errorf("expected 4 dials and 3 reconnects, got %d and %d", dials, bot.reconnects)
+	}
+}
+
+// attempts start over once a session has been playing
+func TestClient_Run_resetsAttemptsAfterMapUpdate(t *testing.T) {
+	dials := 0
+	client := NewClient("test", models.Training, nil, MoveFunc(func(models.MapUpdateEvent) models.Action { return models.Stay }))
+	client.Dial = func(ctx context.Context) (MessageSource, error) {
+		dials++
+		stream := recording(1)
+		if dials == 4 {
+			stream = recording(0)
+		}
+		// cut the recording before the game ends
+		stream = stream[:strings.LastIndex(stream, "\n")]
+		return NewFileSource(strings.NewReader(stream)), nil
+	}
+	client.Reconnect = RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Millisecond, Multiplier: 1}
+
+	if err := client.Run(context.Background()); err == nil {
+		t.Fatal("expected the client to give up")
+	}
+	// sessions 1 to 3 play a tick and start the count over, session 4 uses up the only attempt
+	if dials != 4 {
+		t.Errorf("expected 4 dials, got %d", dials)
+	}
+}
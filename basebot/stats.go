@@ -0,0 +1,32 @@
+package basebot
+
+import "sync"
+
+// Stats counts what the client has done with the map updates it received
+type Stats struct {
+	MapUpdates int
+	MovesSent  int
+	// FallbackMoves is the number of moves where the bot had not proposed anything in time
+	FallbackMoves int
+	// SkippedTicks is the number of map updates never given to the bot because a newer one was already waiting
+	SkippedTicks int
+	// StaleMoves is the number of moves not sent because a newer map update arrived while the bot was thinking
+	StaleMoves int
+}
+
+type statsCounter struct {
+	mux   sync.Mutex
+	stats Stats
+}
+
+func (s *statsCounter) update(f func(stats *Stats)) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	f(&s.stats)
+}
+
+func (s *statsCounter) get() Stats {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.stats
+}
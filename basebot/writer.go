@@ -0,0 +1,104 @@
+package basebot
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/models"
+)
+
+// number of messages of each priority that can wait to be written
+const writeQueueSize = 16
+
+var (
+	errWriteQueueFull = errors.New("write queue is full")
+	errSessionClosed  = errors.New("connection is closed")
+)
+
+// queuedMove is a move waiting to be written, with the time its map update was received
+type queuedMove struct {
+	*models.RegisterMoveEvent
+	received time.Time
+}
+
+// send queues msg to be written by the session's writer without blocking.
+// Moves are written before any other queued messages. Failed writes close the session.
+func (s *session) send(msg interface{}) error {
+	queue := s.normalQueue
+	if _, ok := msg.(*models.RegisterMoveEvent); ok {
+		queue = s.moveQueue
+	}
+	return s.enqueue(queue, msg)
+}
+
+// sendMove queues a move like send, its turnaround from received is measured once it has been written
+func (s *session) sendMove(move *models.RegisterMoveEvent, received time.Time) error {
+	return s.enqueue(s.moveQueue, queuedMove{RegisterMoveEvent: move, received: received})
+}
+
+func (s *session) enqueue(queue chan interface{}, msg interface{}) error {
+	select {
+	case <-s.done:
+		return &Error{Op: OpSend, Err: errSessionClosed}
+	default:
+	}
+
+	select {
+	case queue <- msg:
+		return nil
+	default:
+		return &Error{Op: OpSend, Err: errWriteQueueFull}
+	}
+}
+
+// writer is the only goroutine writing messages to the connection, it runs until the session is closed
+func (s *session) writer(timeout time.Duration) {
+	for {
+		select {
+		case msg := <-s.moveQueue:
+			if !s.write(msg, timeout) {
+				return
+			}
+			continue
+		default:
+		}
+
+		select {
+		case msg := <-s.moveQueue:
+			if !s.write(msg, timeout) {
+				return
+			}
+		case msg := <-s.normalQueue:
+			if !s.write(msg, timeout) {
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *session) write(msg interface{}, timeout time.Duration) bool {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Errorf("failed to encode %T: %v\n", msg, err)
+		return true
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := s.source.WriteMessage(data, deadline); err != nil {
+		s.fail(&Error{Op: OpSend, Err: err})
+		return false
+	}
+	if move, ok := msg.(queuedMove); ok && s.latency != nil {
+		s.latency.moveSent(move.received, time.Now())
+	}
+	s.recorder.Record(Outbound, data)
+	return true
+}
@@ -0,0 +1,144 @@
+package basebot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"paintbot-client/models"
+)
+
+// blockingSource blocks its first write until release is closed and keeps the types of all messages written
+type blockingSource struct {
+	release chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+
+	mux     sync.Mutex
+	written []string
+}
+
+func (b *blockingSource) ReadMessage() ([]byte, error) {
+	<-b.closed
+	return nil, errSessionClosed
+}
+
+func (b *blockingSource) WriteMessage(data []byte, deadline time.Time) error {
+	msg, err := models.Decode(data)
+	if err != nil {
+		return err
+	}
+	b.mux.Lock()
+	first := len(b.written) == 0
+	b.written = append(b.written, msg.MessageType())
+	b.mux.Unlock()
+	if first {
+		<-b.release
+	}
+	return nil
+}
+
+func (b *blockingSource) Close() error {
+	b.once.Do(func() { close(b.closed) })
+	return nil
+}
+
+func (b *blockingSource) types() []string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return append([]string{}, b.written...)
+}
+
+func TestSession_movesAreWrittenFirst(t *testing.T) {
+	source := &blockingSource{release: make(chan struct{}), closed: make(chan struct{})}
+	s := newSession(source, time.Second, nil, nil)
+	defer s.close()
+
+	heartbeat := &models.HearbeatMessage{Type: models.TypeHeartBeatRequest}
+	if err := s.send(heartbeat); err != nil {
+		t.Fatal(err)
+	}
+	// wait for the writer to be stuck writing the first heartbeat
+	for len(source.types()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, msg := range []interface{}{heartbeat, heartbeat, &models.RegisterMoveEvent{Type: models.TypeRegisterMove}} {
+		if err := s.send(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(source.release)
+
+	expected := []string{models.TypeHeartBeatRequest, models.TypeRegisterMove, models.TypeHeartBeatRequest, models.TypeHeartBeatRequest}
+	deadline := time.Now().Add(time.Second)
+	for len(source.types()) < len(expected) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	written := source.types()
+	if strings.Join(written, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, written)
+	}
+}
+
+// failingSource replays a recording but fails writing moves
+type failingSource struct {
+	*FileSource
+	fail func(deadline time.Time) error
+}
+
+func (f *failingSource) WriteMessage(data []byte, deadline time.Time) error {
+	if strings.Contains(string(data), models.TypeRegisterMove) {
+		return f.fail(deadline)
+	}
+	return f.FileSource.WriteMessage(data, deadline)
+}
+
+func runFailingWrites(writeTimeout time.Duration, fail func(deadline time.Time) error) error {
+	source := &failingSource{FileSource: NewFileSource(strings.NewReader(recording(3))), fail: fail}
+	client := NewClient("test", models.Training, nil, MoveFunc(func(models.MapUpdateEvent) models.Action { return models.Stay }))
+	client.Dial = func(ctx context.Context) (MessageSource, error) { return source, nil }
+	client.Reconnect.MaxAttempts = 0
+	client.WriteTimeout = writeTimeout
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return client.Run(ctx)
+}
+
+func TestClient_failedWriteStopsRun(t *testing.T) {
+	broken := errors.New("broken pipe")
+	err := runFailingWrites(time.Second, func(time.Time) error { return broken })
+
+	var e *Error
+	if !errors.As(err, &e) || e.Op != OpSend || !errors.Is(err, broken) {
+		t.Errorf("expected send error wrapping the write error, got %v", err)
+	}
+}
+
+func TestClient_timedOutWriteStopsRun(t *testing.T) {
+	timeout := errors.New("i/o timeout")
+	var mux sync.Mutex
+	var gotDeadline time.Time
+	start := time.Now()
+	err := runFailingWrites(50*time.Millisecond, func(deadline time.Time) error {
+		mux.Lock()
+		gotDeadline = deadline
+		mux.Unlock()
+		// a connection that never accepts the write, as a websocket does when the deadline passes
+		time.Sleep(time.Until(deadline))
+		return timeout
+	})
+
+	var e *Error
+	if !errors.As(err, &e) || e.Op != OpSend || !errors.Is(err, timeout) {
+		t.Errorf("expected send error wrapping the timeout, got %v", err)
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	if gotDeadline.IsZero() || gotDeadline.Sub(start) > time.Second {
+		t.Errorf("expected a write deadline from WriteTimeout, got %v", gotDeadline)
+	}
+}
@@ -0,0 +1,38 @@
+// Package bots contains simple bots to play against locally.
+// Every bot is a calculateMove function, so it can be used with basebot.MoveFunc as well.
+package bots
+
+import (
+	"math/rand"
+
+	"paintbot-client/models"
+	"paintbot-client/utilities/maputility"
+)
+
+var directions = []models.Action{models.Left, models.Right, models.Up, models.Down}
+
+// Random returns a bot that explodes as soon as it carries a power-up and otherwise moves in a random direction it can move in
+func Random(seed int64) func(event models.MapUpdateEvent) models.Action {
+	rng := rand.New(rand.NewSource(seed))
+	return func(event models.MapUpdateEvent) models.Action {
+		utility := maputility.MapUtility{Map: event.Map, CurrentPlayerID: *event.ReceivingPlayerID}
+		me := utility.GetMyCharacterInfo()
+		if me.StunnedForGameTicks > 0 {
+			return models.Stay
+		}
+		if me.CarryingPowerUp {
+			return models.Explode
+		}
+
+		var possible []models.Action
+		for _, action := range directions {
+			if utility.CanIMoveInDirection(action) {
+				possible = append(possible, action)
+			}
+		}
+		if len(possible) == 0 {
+			return models.Stay
+		}
+		return possible[rng.Intn(len(possible))]
+	}
+}
@@ -0,0 +1,61 @@
+package bots
+
+import (
+	"testing"
+
+	"paintbot-client/models"
+)
+
+func update(me models.CharacterInfo, obstacles ...int) models.MapUpdateEvent {
+	id := me.ID
+	return models.MapUpdateEvent{
+		Map: models.Map{
+			Width:               3,
+			Height:              3,
+			CharacterInfos:      []models.CharacterInfo{me},
+			ObstacleUpPositions: obstacles,
+		},
+		ReceivingPlayerID: &id,
+	}
+}
+
+func TestRandom(t *testing.T) {
+	bot := Random(1)
+	for i := 0; i < 20; i++ {
+		// only down is open from the top left corner
+		if action := bot(update(models.CharacterInfo{ID: "me", Position: 0}, 1)); action != models.Down {
+			t.Fatalf("expected DOWN, got %s", action)
+		}
+	}
+	if action := bot(update(models.CharacterInfo{ID: "me", CarryingPowerUp: true})); action != models.Explode {
+		t.Errorf("expected EXPLODE, got %s", action)
+	}
+	if action := bot(update(models.CharacterInfo{ID: "me", Position: 0}, 1, 3)); action != models.Stay {
+		t.Errorf("expected STAY when boxed in, got %s", action)
+	}
+}
+
+func TestGreedy(t *testing.T) {
+	// tiles 0, 1 and 3 are coloured, the closest tile left is 2 via 1
+	me := models.CharacterInfo{ID: "me", Position: 0, ColouredPosition: []int{0, 1, 3}}
+	if action := Greedy()(update(me)); action != models.Right {
+		t.Errorf("expected RIGHT, got %s", action)
+	}
+}
+
+func TestHunter(t *testing.T) {
+	event := update(models.CharacterInfo{ID: "me", Position: 0}, 1)
+	event.Map.PowerUpPositions = []int{8}
+	if action := Hunter(1)(event); action != models.Down {
+		t.Errorf("expected DOWN towards the power-up, got %s", action)
+	}
+
+	event = update(models.CharacterInfo{ID: "me", Position: 0, CarryingPowerUp: true})
+	event.Map.CharacterInfos = append(event.Map.CharacterInfos, models.CharacterInfo{ID: "other", Position: 8})
+	if action := Hunter(1)(event); action == models.Explode {
+		t.Error("exploded without anyone within reach")
+	}
+	if action := Hunter(2)(event); action != models.Explode {
+		t.Errorf("expected EXPLODE, got %s", action)
+	}
+}
@@ -0,0 +1,131 @@
+package bots
+
+import (
+	"paintbot-client/models"
+	"paintbot-client/utilities/maputility"
+)
+
+// Greedy returns a bot that walks to the closest tile it has not coloured, exploding as soon as it carries a power-up
+func Greedy() func(event models.MapUpdateEvent) models.Action {
+	return func(event models.MapUpdateEvent) models.Action {
+		utility := maputility.MapUtility{Map: event.Map, CurrentPlayerID: *event.ReceivingPlayerID}
+		me := utility.GetMyCharacterInfo()
+		if me.StunnedForGameTicks > 0 {
+			return models.Stay
+		}
+		if me.CarryingPowerUp {
+			return models.Explode
+		}
+		return towards(event.Map, me, notColouredBy(me))
+	}
+}
+
+// Hunter returns a bot that collects power-ups and only explodes when another player is within reach tiles,
+// without power-ups around it colours tiles like Greedy
+func Hunter(reach int) func(event models.MapUpdateEvent) models.Action {
+	return func(event models.MapUpdateEvent) models.Action {
+		utility := maputility.MapUtility{Map: event.Map, CurrentPlayerID: *event.ReceivingPlayerID}
+		me := utility.GetMyCharacterInfo()
+		if me.StunnedForGameTicks > 0 {
+			return models.Stay
+		}
+		if me.CarryingPowerUp {
+			if opponentWithin(event.Map, me, reach) {
+				return models.Explode
+			}
+			return towards(event.Map, me, notColouredBy(me))
+		}
+
+		powerUps := map[int]bool{}
+		for _, pos := range event.Map.PowerUpPositions {
+			powerUps[pos] = true
+		}
+		if action := towards(event.Map, me, func(pos int) bool { return powerUps[pos] }); action != models.Stay {
+			return action
+		}
+		return towards(event.Map, me, notColouredBy(me))
+	}
+}
+
+func notColouredBy(me models.CharacterInfo) func(pos int) bool {
+	coloured := map[int]bool{}
+	for _, pos := range me.ColouredPosition {
+		coloured[pos] = true
+	}
+	return func(pos int) bool { return !coloured[pos] }
+}
+
+func opponentWithin(m models.Map, me models.CharacterInfo, reach int) bool {
+	for _, c := range m.CharacterInfos {
+		if c.ID == me.ID {
+			continue
+		}
+		dx, dy := c.Position%m.Width-me.Position%m.Width, c.Position/m.Width-me.Position/m.Width
+		if abs(dx) <= reach && abs(dy) <= reach {
+			return true
+		}
+	}
+	return false
+}
+
+// towards returns the first step on a shortest path to the closest tile matching goal, or STAY if there is none
+func towards(m models.Map, me models.CharacterInfo, goal func(pos int) bool) models.Action {
+	blocked := map[int]bool{}
+	for _, pos := range m.ObstacleUpPositions {
+		blocked[pos] = true
+	}
+	for _, c := range m.CharacterInfos {
+		if c.ID != me.ID {
+			blocked[c.Position] = true
+		}
+	}
+
+	// the first action taken to reach each visited tile
+	first := map[int]models.Action{me.Position: models.Stay}
+	queue := []int{me.Position}
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		if pos != me.Position && goal(pos) {
+			return first[pos]
+		}
+		for _, action := range directions {
+			next, ok := step(m, pos, action)
+			if _, seen := first[next]; !ok || seen || blocked[next] {
+				continue
+			}
+			if pos == me.Position {
+				first[next] = action
+			} else {
+				first[next] = first[pos]
+			}
+			queue = append(queue, next)
+		}
+	}
+	return models.Stay
+}
+
+func step(m models.Map, pos int, action models.Action) (int, bool) {
+	x, y := pos%m.Width, pos/m.Width
+	switch action {
+	case models.Left:
+		x--
+	case models.Right:
+		x++
+	case models.Up:
+		y--
+	case models.Down:
+		y++
+	}
+	if x < 0 || y < 0 || x >= m.Width || y >= m.Height {
+		return pos, false
+	}
+	return y*m.Width + x, true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
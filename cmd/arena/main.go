@@ -0,0 +1,246 @@
+// Command arena plays bots against each other locally, without a server, and compares how they do.
+//
+//	go run ./cmd/arena -bots random,greedy,hunter -games 200
+//
+// Any calculateMove function can take part by adding it to strategies.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/bots"
+	"paintbot-client/engine"
+	"paintbot-client/mapgen"
+	"paintbot-client/models"
+)
+
+// strategy creates a bot for a game, seed is for bots making random choices
+type strategy func(seed int64) func(event models.MapUpdateEvent) models.Action
+
+var strategies = map[string]strategy{
+	"random": bots.Random,
+	"greedy": func(int64) func(models.MapUpdateEvent) models.Action { return bots.Greedy() },
+	"hunter": func(int64) func(models.MapUpdateEvent) models.Action { return bots.Hunter(3) },
+}
+
+var baseSettings = models.GameSettings{
+	MaxNOOFPlayers:                 5,
+	TimeInMSPerTick:                250,
+	ObstaclesEnabled:               true,
+	PowerUpsEnabled:                true,
+	AddPowerUpLikelihood:           38,
+	RemovePowerUpLikelihood:        5,
+	PointsPerTileOwned:             1,
+	PointsPerCausedStun:            5,
+	NOOFTicksInvulnerableAfterStun: 3,
+	NOOFTicksStunned:               10,
+	StartObstacles:                 40,
+	StartPowerUps:                  41,
+	GameDurationInSeconds:          60,
+	ExplosionRange:                 4,
+}
+
+type contestant struct {
+	name     string
+	strategy strategy
+}
+
+// result is how a contestant did in one game
+type result struct {
+	won    bool
+	points int
+	stuns  int
+	tiles  int
+}
+
+func main() {
+	botNames := flag.String("bots", "random,greedy,hunter", "comma separated bots to play, the same bot can be given several times")
+	games := flag.Int("games", 100, "number of games to play")
+	seed := flag.Int64("seed", 1, "seed of the first game, the following games use the next seeds")
+	vary := flag.Bool("vary", true, "vary map size and settings between games")
+	mapWidth := flag.Int("width", 30, "map width when not varying settings")
+	mapHeight := flag.Int("height", 20, "map height when not varying settings")
+	mapFile := flag.String("map", "", "play all games on the layout in this file instead of generated maps")
+	parallel := flag.Int("parallel", runtime.NumCPU(), "games played at the same time")
+	flag.Parse()
+
+	contestants, err := parseContestants(*botNames)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *games < 1 || *parallel < 1 {
+		log.Fatal("games and parallel must be at least 1")
+	}
+	var fixed mapgen.Layout
+	if *mapFile != "" {
+		if fixed, err = mapgen.LoadFile(*mapFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	results := make([][]result, *games)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < *parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				gameSeed := *seed + int64(i)
+				settings, width, height := baseSettings, *mapWidth, *mapHeight
+				if *vary {
+					settings, width, height = varySettings(rand.New(rand.NewSource(gameSeed)))
+				}
+				layout := fixed
+				if *mapFile == "" {
+					generated, err := mapgen.Generate(gameSeed, width, height, settings, len(contestants))
+					if err != nil {
+						log.Fatalf("game %d: %v", i, err)
+					}
+					layout = generated
+				}
+				r, err := play(contestants, settings, layout, gameSeed)
+				if err != nil {
+					log.Fatalf("game %d: %v", i, err)
+				}
+				results[i] = r
+			}
+		}()
+	}
+	for i := 0; i < *games; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	report(contestants, results)
+}
+
+// parseContestants names bots given more than once bot, bot#2...
+func parseContestants(names string) ([]contestant, error) {
+	var contestants []contestant
+	count := map[string]int{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		s, ok := strategies[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown bot %q, known bots are %s", name, strings.Join(strategyNames(), ", "))
+		}
+		count[name]++
+		if count[name] > 1 {
+			name = fmt.Sprintf("%s#%d", name, count[name])
+		}
+		contestants = append(contestants, contestant{name: name, strategy: s})
+	}
+	if len(contestants) < 2 {
+		return nil, fmt.Errorf("at least two bots are needed")
+	}
+	return contestants, nil
+}
+
+func strategyNames() []string {
+	var names []string
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// varySettings picks map size and settings for a game
+func varySettings(rng *rand.Rand) (models.GameSettings, int, int) {
+	sizes := [][2]int{{20, 15}, {30, 20}, {40, 25}}
+	size := sizes[rng.Intn(len(sizes))]
+
+	settings := baseSettings
+	settings.ExplosionRange = 2 + rng.Intn(4)
+	settings.StartObstacles = 10 + rng.Intn(51)
+	settings.StartPowerUps = 5 + rng.Intn(36)
+	settings.AddPowerUpLikelihood = 10 + rng.Intn(41)
+	settings.RemovePowerUpLikelihood = rng.Intn(11)
+	return settings, size[0], size[1]
+}
+
+// play plays a game and returns the results in the order of the contestants
+func play(contestants []contestant, settings models.GameSettings, layout mapgen.Layout, seed int64) ([]result, error) {
+	rng := rand.New(rand.NewSource(seed))
+	players := make([]engine.Player, len(contestants))
+	moves := map[string]func(models.MapUpdateEvent) models.Action{}
+	contestantIndex := map[string]int{}
+	// shuffled so no contestant always gets the same spawn point
+	for i, c := range rng.Perm(len(contestants)) {
+		id := fmt.Sprintf("player-%d", c)
+		players[i] = engine.Player{ID: id, Name: contestants[c].name}
+		moves[id] = contestants[c].strategy(rng.Int63())
+		contestantIndex[id] = c
+	}
+
+	g, err := engine.New(fmt.Sprintf("game-%d", seed), settings, layout, players, rng.Int63())
+	if err != nil {
+		return nil, err
+	}
+	for !g.Finished() {
+		actions := map[string]models.Action{}
+		for _, p := range players {
+			actions[p.ID] = moves[p.ID](g.MapUpdate(p.ID))
+		}
+		g.Step(actions)
+	}
+
+	won := map[string]bool{}
+	for _, rank := range g.Ranks() {
+		won[rank.PlayerId] = rank.Rank == 1
+	}
+	results := make([]result, len(contestants))
+	for _, s := range g.Stats() {
+		results[contestantIndex[s.ID]] = result{won: won[s.ID], points: s.Points, stuns: s.StunsCaused, tiles: s.TilesOwned}
+	}
+	return results, nil
+}
+
+func report(contestants []contestant, results [][]result) {
+	type summary struct {
+		name   string
+		wins   int
+		points sample
+		stuns  sample
+		tiles  sample
+	}
+	summaries := make([]summary, len(contestants))
+	for i, c := range contestants {
+		summaries[i].name = c.name
+		for _, game := range results {
+			r := game[i]
+			if r.won {
+				summaries[i].wins++
+			}
+			summaries[i].points.add(float64(r.points))
+			summaries[i].stuns.add(float64(r.stuns))
+			summaries[i].tiles.add(float64(r.tiles))
+		}
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].wins > summaries[j].wins
+	})
+
+	fmt.Printf("%d games, intervals are 95%% confidence, ties count as wins for all tied bots\n\n", len(results))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "bot\twin rate\tpoints\tstuns caused\ttiles")
+	for _, s := range summaries {
+		low, high := wilson(s.wins, len(results))
+		fmt.Fprintf(w, "%s\t%.1f%% [%.1f-%.1f]\t%.1f ± %.1f\t%.2f ± %.2f\t%.1f ± %.1f\n", s.name,
+			100*float64(s.wins)/float64(len(results)), 100*low, 100*high,
+			s.points.mean(), s.points.ci(), s.stuns.mean(), s.stuns.ci(), s.tiles.mean(), s.tiles.ci())
+	}
+	w.Flush()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+)
+
+// z for a 95% confidence interval
+const z95 = 1.96
+
+// sample accumulates a value per game
+type sample struct {
+	n     int
+	sum   float64
+	sumSq float64
+}
+
+func (s *sample) add(v float64) {
+	s.n++
+	s.sum += v
+	s.sumSq += v * v
+}
+
+func (s sample) mean() float64 {
+	if s.n == 0 {
+		return 0
+	}
+	return s.sum / float64(s.n)
+}
+
+// ci returns the half width of the 95% confidence interval of the mean
+func (s sample) ci() float64 {
+	if s.n < 2 {
+		return math.Inf(1)
+	}
+	n := float64(s.n)
+	variance := (s.sumSq - s.sum*s.sum/n) / (n - 1)
+	if variance < 0 {
+		variance = 0
+	}
+	return z95 * math.Sqrt(variance/n)
+}
+
+// wilson returns the 95% Wilson score interval of a win rate, which unlike the normal
+// approximation stays within 0 and 1 for few games and rates close to 0 or 1
+func wilson(wins, games int) (low, high float64) {
+	if games == 0 {
+		return 0, 1
+	}
+	n := float64(games)
+	p := float64(wins) / n
+	denominator := 1 + z95*z95/n
+	centre := (p + z95*z95/(2*n)) / denominator
+	margin := z95 * math.Sqrt(p*(1-p)/n+z95*z95/(4*n*n)) / denominator
+	return math.Max(0, centre-margin), math.Min(1, centre+margin)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	var s sample
+	for _, v := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		s.add(v)
+	}
+	// sample standard deviation is sqrt(32/7)
+	expected := z95 * math.Sqrt(32.0/7/8)
+	if s.mean() != 5 || math.Abs(s.ci()-expected) > 1e-9 {
+		t.Errorf("expected 5 ± %f, got %f ± %f", expected, s.mean(), s.ci())
+	}
+}
+
+func TestWilson(t *testing.T) {
+	low, high := wilson(0, 10)
+	if low != 0 || high < 0.25 || high > 0.35 {
+		t.Errorf("unexpected interval [%f, %f] for 0 of 10", low, high)
+	}
+	low, high = wilson(50, 100)
+	if math.Abs(low+high-1) > 1e-9 || low < 0.39 || low > 0.41 {
+		t.Errorf("unexpected interval [%f, %f] for 50 of 100", low, high)
+	}
+}
@@ -1,13 +1,62 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+
 	"paintbot-client/basebot"
 	"paintbot-client/models"
 	"paintbot-client/utilities/maputility"
 )
 
 func main() {
-	basebot.Start("Simple Go Bot", models.Training, desiredGameSettings, calculateMove)
+	opts := basebot.DefaultConnectionOptions()
+	if err := opts.LoadEnv(); err != nil {
+		log.Fatal(err)
+	}
+	opts.RegisterFlags(flag.CommandLine)
+	mode := flag.String("mode", "training", "game mode: training, tournament, arena:<name> or a server path")
+	record := flag.String("record", "", "directory to record all messages to, one file per game")
+	replay := flag.String("replay", "", "play against a file of recorded server messages instead of a server")
+	flag.Parse()
+
+	gameMode, err := models.ParseGameMode(*mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		sig := <-signals
+		log.Infof("Received %s, shutting down\n", sig)
+		cancel()
+	}()
+
+	if *replay != "" {
+		err = basebot.Replay(ctx, *replay, "Simple Go Bot", gameMode, basebot.MoveFunc(calculateMove))
+	} else {
+		client := basebot.NewClient("Simple Go Bot", gameMode, desiredGameSettings, basebot.MoveFunc(calculateMove))
+		client.Connection = opts
+		if *record != "" {
+			if client.Recorder, err = basebot.NewRecorder(*record); err != nil {
+				log.Fatal(err)
+			}
+			defer client.Recorder.Close()
+		}
+		err = client.Run(ctx)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatal(err)
+	}
 }
 
 var moves = []models.Action{models.Explode, models.Left, models.Down, models.Right, models.Up} //, models.Stay}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/bots"
+	"paintbot-client/engine"
+	"paintbot-client/mapgen"
+	"paintbot-client/models"
+)
+
+// play runs a game between the players and the built-in bots, a tick is played as soon as
+// all players have moved or when the tick time is up
+func (s *server) play(mode models.GameMode, players []*player, settings models.GameSettings) {
+	gameID := s.newID("game")
+	var participants []engine.Player
+	for _, p := range players {
+		participants = append(participants, engine.Player{ID: p.id, Name: p.playerName()})
+	}
+	builtIn := map[string]func(models.MapUpdateEvent) models.Action{}
+	for i := 1; i <= s.bots; i++ {
+		id := s.newID("bot")
+		participants = append(participants, engine.Player{ID: id, Name: fmt.Sprintf("Random Bot %d", i)})
+		builtIn[id] = bots.Random(s.nextSeed())
+	}
+
+	g, err := s.newGame(gameID, settings, participants)
+	if err != nil {
+		log.Errorf("failed to create game on %s: %v\n", mode, err)
+		for _, p := range players {
+			p.invalid(nil, fmt.Errorf("failed to create game: %v", err))
+			p.send(closeConnection{})
+		}
+		return
+	}
+
+	moves := make(chan move, moveQueueSize)
+	for _, p := range players {
+		p.play(moves)
+		defer p.play(nil)
+	}
+	log.Infof("Starting %s on %s with %d players\n", gameID, mode, len(participants))
+
+	start := g.Map()
+	for _, p := range players {
+		p.send(models.GameLinkEvent{
+			Type:   models.TypeGameLink,
+			GameID: gameID,
+			// there is no game viewer locally
+			URL:               "",
+			ReceivingPlayerID: &p.id,
+			Timestamp:         timestamp(),
+		})
+		p.send(models.GameStartingEvent{
+			Type:              models.TypeGameStarting,
+			GameID:            gameID,
+			NOOFPlayers:       len(participants),
+			Width:             start.Width,
+			Height:            start.Height,
+			GameSettings:      settings,
+			ReceivingPlayerID: &p.id,
+			Timestamp:         timestamp(),
+		})
+	}
+
+	tickDuration := time.Duration(settings.TimeInMSPerTick) * time.Millisecond
+	for !g.Finished() {
+		for _, p := range players {
+			update := g.MapUpdate(p.id)
+			update.Timestamp = timestamp()
+			p.send(update)
+		}
+
+		actions := map[string]models.Action{}
+		for id, bot := range builtIn {
+			actions[id] = bot(g.MapUpdate(id))
+		}
+		collectMoves(g, players, moves, actions, tickDuration)
+		g.Step(actions)
+	}
+
+	ranks := g.Ranks()
+	for _, p := range players {
+		p.send(models.GameResultEvent{
+			Type:              models.TypeGameResult,
+			GameID:            gameID,
+			PlayerRanks:       ranks,
+			ReceivingPlayerID: &p.id,
+			Timestamp:         timestamp(),
+		})
+		p.send(models.GameEndedEvent{
+			Type:              models.TypeGameEnded,
+			PlayerWinnerID:    ranks[0].PlayerId,
+			PlayerWinnerName:  ranks[0].PlayerName,
+			GameID:            gameID,
+			GameTick:          g.Tick(),
+			Map:               g.Map(),
+			ReceivingPlayerID: &p.id,
+			Timestamp:         timestamp(),
+		})
+		if mode == models.Tournament {
+			p.send(tournamentEnded(gameID, ranks, p.id))
+		}
+	}
+
+	for _, rank := range ranks {
+		log.Infof("%s: %d. %s with %d points\n", gameID, rank.Rank, rank.PlayerName, rank.Points)
+	}
+}
+
+// newGame creates a game on the fixed layout if there is one, otherwise on a generated map
+func (s *server) newGame(gameID string, settings models.GameSettings, participants []engine.Player) (*engine.Game, error) {
+	layout := s.layout
+	if layout == nil {
+		generated, err := mapgen.Generate(s.nextSeed(), s.width, s.height, settings, len(participants))
+		if err != nil {
+			return nil, err
+		}
+		layout = &generated
+	}
+	return engine.New(gameID, settings, *layout, participants, s.nextSeed())
+}
+
+// collectMoves waits until all players still connected have moved in the current tick, or the timeout
+func collectMoves(g *engine.Game, players []*player, moves <-chan move, actions map[string]models.Action, timeout time.Duration) {
+	waiting := map[string]bool{}
+	for _, p := range players {
+		if !p.left() {
+			waiting[p.id] = true
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for len(waiting) > 0 {
+		select {
+		case m := <-moves:
+			if m.left {
+				delete(waiting, m.playerID)
+				continue
+			}
+			if m.event.GameID != g.ID() || m.event.GameTick != g.Tick() || !waiting[m.playerID] {
+				log.Debugf("ignoring move from %s for tick %d in %s\n", m.playerID, m.event.GameTick, m.event.GameID)
+				continue
+			}
+			actions[m.playerID] = models.Action(m.event.Action)
+			delete(waiting, m.playerID)
+		case <-timer.C:
+			log.Debugf("%s tick %d: no move from %d players\n", g.ID(), g.Tick(), len(waiting))
+			return
+		}
+	}
+}
+
+// tournamentEnded lets players on /tournament know they are done, a local tournament is a single game
+func tournamentEnded(gameID string, ranks []models.PlayerRank, playerID string) models.TournamentEndedEvent {
+	var result []models.PlayerPoint
+	for _, rank := range ranks {
+		result = append(result, models.PlayerPoint{Name: rank.PlayerName, PlayerID: rank.PlayerId, Points: rank.Points})
+	}
+	return models.TournamentEndedEvent{
+		Type:              models.TypeTournamentEnded,
+		PlayerWinnerID:    ranks[0].PlayerId,
+		GameID:            gameID,
+		GameResult:        result,
+		TournamentName:    "Local tournament",
+		TournamentID:      gameID,
+		ReceivingPlayerID: &playerID,
+		Timestamp:         timestamp(),
+	}
+}
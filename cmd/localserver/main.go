@@ -0,0 +1,67 @@
+// Command localserver is a stand-in for the paintbot server, to run full games offline.
+//
+// It speaks the same protocol as the real server, so bots in any language can connect to it, e.g.
+//
+//	go run ./cmd/localserver -bots 3
+//	go run ./cmd/examplebot -host localhost -port 8080
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/mapgen"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	players := flag.Int("players", 1, "connected players needed to start a game outside training")
+	builtIn := flag.Int("bots", 3, "built-in random bots added to every game")
+	width := flag.Int("width", 30, "map width")
+	height := flag.Int("height", 20, "map height")
+	mapFile := flag.String("map", "", "play all games on the layout in this file instead of generated maps")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed for the maps and the built-in bots")
+	verbose := flag.Bool("v", false, "verbose logging")
+	flag.Parse()
+
+	if *verbose {
+		log.SetLevel(log.DebugLevel)
+	}
+	if *players < 1 {
+		log.Fatal("at least one player is needed")
+	}
+
+	s := newServer(*players, *builtIn, *width, *height, *seed)
+	if *mapFile != "" {
+		layout, err := mapgen.LoadFile(*mapFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s.layout = &layout
+	}
+
+	srv := &http.Server{Addr: *addr, Handler: s}
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		sig := <-signals
+		log.Infof("Received %s, shutting down\n", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Warnf("shutdown: %v\n", err)
+		}
+	}()
+
+	log.Infof("Listening on %s\n", *addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
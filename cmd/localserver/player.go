@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/models"
+)
+
+const (
+	writeTimeout  = 5 * time.Second
+	outQueueSize  = 32
+	moveQueueSize = 64
+)
+
+// player is a connected client
+type player struct {
+	id   string
+	conn *websocket.Conn
+	mode models.GameMode
+	out  chan interface{}
+	done chan struct{}
+
+	mux      sync.Mutex
+	name     string
+	settings *models.GameSettings
+	// joined is set from StartGame until the game the player joined has ended
+	joined bool
+	moves  chan<- move
+}
+
+// closeConnection makes the writer close the connection once everything queued before it is written
+type closeConnection struct{}
+
+// move is a move registered by a player, or a notice that the player left when left is set
+type move struct {
+	playerID string
+	event    models.RegisterMoveEvent
+	left     bool
+}
+
+func newPlayer(id string, conn *websocket.Conn, mode models.GameMode) *player {
+	p := &player{
+		id:   id,
+		conn: conn,
+		mode: mode,
+		out:  make(chan interface{}, outQueueSize),
+		done: make(chan struct{}),
+	}
+	go p.writer()
+	return p
+}
+
+// send queues a message to the player, messages to players that have left are dropped
+func (p *player) send(msg interface{}) {
+	select {
+	case p.out <- msg:
+	case <-p.done:
+	}
+}
+
+func (p *player) writer() {
+	for {
+		select {
+		case msg := <-p.out:
+			if _, ok := msg.(closeConnection); ok {
+				closeMSG := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+				_ = p.conn.WriteControl(websocket.CloseMessage, closeMSG, time.Now().Add(writeTimeout))
+				p.conn.Close()
+				return
+			}
+			if err := p.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+				log.Warnf("%s: %v\n", p.id, err)
+			}
+			if err := p.conn.WriteJSON(msg); err != nil {
+				log.Warnf("failed to write to %s: %v\n", p.id, err)
+				p.conn.Close()
+				return
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// join returns false if the player has already joined a lobby or game, otherwise it marks the player as joined
+func (p *player) join() bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.joined {
+		return false
+	}
+	p.joined = true
+	return true
+}
+
+// play makes the player's moves go to the game reading moves, nil when the game has ended
+func (p *player) play(moves chan<- move) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.moves = moves
+	if moves == nil {
+		p.joined = false
+	}
+}
+
+func (p *player) forward(m move) {
+	p.mux.Lock()
+	moves := p.moves
+	p.mux.Unlock()
+	if moves == nil {
+		log.Debugf("%s is not in a game, ignoring move\n", p.id)
+		return
+	}
+	select {
+	case moves <- m:
+	default:
+		log.Warnf("too many moves from %s, dropping move\n", p.id)
+	}
+}
+
+func (p *player) registered() bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.name != ""
+}
+
+func (p *player) playerName() string {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.name
+}
+
+// receive reads messages from the player until the connection is closed
+func (p *player) receive(s *server) {
+	defer func() {
+		close(p.done)
+		p.forward(move{playerID: p.id, left: true})
+		p.conn.Close()
+	}()
+
+	for {
+		_, data, err := p.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Infof("%s disconnected: %v\n", p.id, err)
+			}
+			return
+		}
+
+		msg, err := models.Decode(data)
+		if err != nil {
+			p.invalid(data, err)
+			continue
+		}
+		if _, ok := msg.(models.RegisterPlayerEvent); !ok && !p.registered() {
+			p.invalid(data, errors.New("player not registered"))
+			continue
+		}
+
+		switch m := msg.(type) {
+		case models.RegisterPlayerEvent:
+			p.register(m)
+		case models.StartGameEvent:
+			s.join(p)
+		case models.ClientInfoMSG:
+			log.Infof("%s is using %s %s on %s %s, client version %s\n", p.playerName(),
+				m.Language, m.LanguageVersion, m.OperatingSystem, m.OperatingSystemVersion, m.ClientVersion)
+		case models.RegisterMoveEvent:
+			if !validAction(models.Action(m.Action)) {
+				p.invalid(data, fmt.Errorf("invalid direction %q", m.Action))
+				continue
+			}
+			p.forward(move{playerID: p.id, event: m})
+		case models.HearbeatMessage:
+			p.send(models.HeartBeatResponse{
+				Type:              models.TypeHeartBeatResponse,
+				ReceivingPlayerID: &p.id,
+				Timestamp:         timestamp(),
+			})
+		default:
+			p.invalid(data, fmt.Errorf("unexpected message %s", msg.MessageType()))
+		}
+	}
+}
+
+func (p *player) register(event models.RegisterPlayerEvent) {
+	p.mux.Lock()
+	p.name = event.PlayerName
+	if p.name == "" {
+		p.name = p.id
+	}
+	p.settings = event.GameSettings
+	p.mux.Unlock()
+
+	log.Infof("%s registered as %s on %s\n", p.playerName(), p.id, p.mode)
+	p.send(models.PlayerRegisteredEvent{
+		Type:              models.TypePlayerRegistered,
+		PlayerName:        p.playerName(),
+		GameSettings:      p.gameSettings(),
+		GameMode:          gameModeName(p.mode),
+		ReceivingPlayerID: &p.id,
+		Timestamp:         timestamp(),
+	})
+}
+
+// gameSettings are the settings the player asked for in training games, the defaults otherwise
+func (p *player) gameSettings() models.GameSettings {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.mode == models.Training && p.settings != nil {
+		return *p.settings
+	}
+	return defaultSettings
+}
+
+func (p *player) invalid(data []byte, err error) {
+	log.Debugf("invalid message from %s: %v\n", p.id, err)
+	p.send(models.InvalidMessage{
+		Type:              models.TypeInvalidMessage,
+		ErrorMessage:      err.Error(),
+		ReceivedMessage:   string(data),
+		ReceivingPlayerID: &p.id,
+		Timestamp:         timestamp(),
+	})
+}
+
+func validAction(action models.Action) bool {
+	switch action {
+	case models.Left, models.Right, models.Up, models.Down, models.Stay, models.Explode:
+		return true
+	}
+	return false
+}
+
+// gameModeName is the name the server uses for the game mode in PlayerRegistered
+func gameModeName(mode models.GameMode) string {
+	switch mode {
+	case models.Training:
+		return "TRAINING"
+	case models.Tournament:
+		return "TOURNAMENT"
+	}
+	return "ARENA"
+}
+
+func timestamp() int {
+	return int(time.Now().UnixNano() / int64(time.Millisecond))
+}
+
+func (p *player) left() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/mapgen"
+	"paintbot-client/models"
+)
+
+// defaultSettings are used for games where the players can not choose settings
+var defaultSettings = models.GameSettings{
+	MaxNOOFPlayers:                 5,
+	TimeInMSPerTick:                250,
+	ObstaclesEnabled:               true,
+	PowerUpsEnabled:                true,
+	AddPowerUpLikelihood:           38,
+	RemovePowerUpLikelihood:        5,
+	PointsPerTileOwned:             1,
+	PointsPerCausedStun:            5,
+	NOOFTicksInvulnerableAfterStun: 3,
+	NOOFTicksStunned:               10,
+	StartObstacles:                 40,
+	StartPowerUps:                  41,
+	GameDurationInSeconds:          15,
+	ExplosionRange:                 4,
+}
+
+// server runs games for the players connecting to it. Every path is a separate lobby:
+// on /training each player gets a game of its own, on other paths a game starts once enough players have joined.
+type server struct {
+	upgrader websocket.Upgrader
+	// players needed to start a game outside training
+	players int
+	// built-in bots added to every game
+	bots   int
+	width  int
+	height int
+	// layout all games are played on instead of generated maps, if set
+	layout *mapgen.Layout
+
+	mux     sync.Mutex
+	rng     *rand.Rand
+	nextID  int
+	lobbies map[models.GameMode][]*player
+}
+
+func newServer(players, bots, width, height int, seed int64) *server {
+	return &server{
+		players: players,
+		bots:    bots,
+		width:   width,
+		height:  height,
+		rng:     rand.New(rand.NewSource(seed)),
+		lobbies: map[models.GameMode][]*player{},
+	}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warnf("failed to upgrade connection from %s: %v\n", r.RemoteAddr, err)
+		return
+	}
+	p := newPlayer(s.newID("player"), conn, models.GameMode(r.URL.Path))
+	log.Debugf("%s connected from %s to %s\n", p.id, r.RemoteAddr, p.mode)
+	p.receive(s)
+}
+
+// join adds a player that asked to start a game to the lobby of its path, starting the game when the lobby is full
+func (s *server) join(p *player) {
+	if !p.join() {
+		log.Debugf("%s has already joined a game\n", p.id)
+		return
+	}
+	if p.mode == models.Training {
+		go s.play(p.mode, []*player{p}, p.gameSettings())
+		return
+	}
+
+	s.mux.Lock()
+	lobby := append(s.lobbies[p.mode], p)
+	if len(lobby) < s.players {
+		s.lobbies[p.mode] = lobby
+		s.mux.Unlock()
+		log.Infof("%d of %d players waiting on %s\n", len(lobby), s.players, p.mode)
+		return
+	}
+	delete(s.lobbies, p.mode)
+	s.mux.Unlock()
+
+	go s.play(p.mode, lobby, defaultSettings)
+}
+
+func (s *server) newID(prefix string) string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.nextID++
+	return fmt.Sprintf("%s-%d", prefix, s.nextID)
+}
+
+func (s *server) nextSeed() int64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.rng.Int63()
+}
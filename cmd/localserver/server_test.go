@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"paintbot-client/basebot"
+	"paintbot-client/models"
+)
+
+type resultBot struct {
+	basebot.MoveFunc
+	ended chan models.GameEndedEvent
+}
+
+func (b *resultBot) OnGameEnded(event models.GameEndedEvent) {
+	b.ended <- event
+}
+
+func TestServer_trainingGame(t *testing.T) {
+	ts := httptest.NewServer(newServer(1, 2, 10, 10, 1))
+	defer ts.Close()
+
+	address := strings.TrimPrefix(ts.URL, "http://")
+	host := address[:strings.LastIndex(address, ":")]
+	port, _ := strconv.Atoi(address[strings.LastIndex(address, ":")+1:])
+
+	moves := 0
+	bot := &resultBot{
+		MoveFunc: func(event models.MapUpdateEvent) models.Action {
+			moves++
+			return models.Right
+		},
+		ended: make(chan models.GameEndedEvent, 1),
+	}
+	settings := defaultSettings
+	settings.GameDurationInSeconds = 1
+
+	client := basebot.NewClient("tester", models.Training, &settings, bot)
+	client.Connection.Host = host
+	client.Connection.Port = port
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ended := <-bot.ended
+	if ended.GameTick != 4 || len(ended.Map.CharacterInfos) != 3 {
+		t.Errorf("unexpected end of game %+v", ended)
+	}
+	if moves != 4 {
+		t.Errorf("expected 4 map updates, got %d", moves)
+	}
+}
+
+// dial connects to the training lobby of ts and registers, returning the connection
+func dial(t *testing.T, ts *httptest.Server, settings *models.GameSettings) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+string(models.Training), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(models.RegisterPlayerEvent{Type: models.TypeRegisterPlayer, PlayerName: "raw", GameSettings: settings}); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// readUntil reads messages until one of type last, or the connection closes, and returns the types read
+func readUntil(t *testing.T, conn *websocket.Conn, last string) []string {
+	var types []string
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return types
+		}
+		msg, err := models.Decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, msg.MessageType())
+		if msg.MessageType() == last {
+			return types
+		}
+	}
+}
+
+func count(types []string, messageType string) int {
+	n := 0
+	for _, t := range types {
+		if t == messageType {
+			n++
+		}
+	}
+	return n
+}
+
+func TestServer_startGameTwiceStartsOneGame(t *testing.T) {
+	ts := httptest.NewServer(newServer(1, 1, 10, 10, 1))
+	defer ts.Close()
+
+	settings := defaultSettings
+	settings.GameDurationInSeconds = 1
+	settings.TimeInMSPerTick = 50
+	conn := dial(t, ts, &settings)
+	defer conn.Close()
+	start := models.StartGameEvent{Type: models.TypeStartGame}
+	for i := 0; i < 2; i++ {
+		if err := conn.WriteJSON(start); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	types := readUntil(t, conn, models.TypeGameEnded)
+	if count(types, models.TypeGameStarting) != 1 || count(types, models.TypeGameEnded) != 1 {
+		t.Errorf("expected a single game, got %v", types)
+	}
+	if count(types, models.TypeMapUpdate) != 20 {
+		t.Errorf("expected 20 map updates, got %d", count(types, models.TypeMapUpdate))
+	}
+}
+
+func TestServer_failedGameIsReported(t *testing.T) {
+	// too many bots for the map
+	ts := httptest.NewServer(newServer(1, 10, 2, 2, 1))
+	defer ts.Close()
+
+	conn := dial(t, ts, nil)
+	defer conn.Close()
+	if err := conn.WriteJSON(models.StartGameEvent{Type: models.TypeStartGame}); err != nil {
+		t.Fatal(err)
+	}
+
+	types := readUntil(t, conn, "")
+	if len(types) != 2 || types[0] != models.TypePlayerRegistered || types[1] != models.TypeInvalidMessage {
+		t.Errorf("expected registration and an invalid message before the connection closed, got %v", types)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"paintbot-client/basebot"
+	"paintbot-client/models"
+)
+
+// loadMapUpdates reads the map updates from a file with one server message per line.
+// Recordings made by basebot.Recorder are read as well, then only received messages are used.
+func loadMapUpdates(path string) ([]models.MapUpdateEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readMapUpdates(file)
+}
+
+func readMapUpdates(r io.Reader) ([]models.MapUpdateEvent, error) {
+	recording := basebot.NewRecordingReader(r)
+
+	var updates []models.MapUpdateEvent
+	for {
+		line, err := recording.Next()
+		if err == io.EOF {
+			return updates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := models.Decode(line)
+		if err != nil {
+			if _, ok := err.(*models.UnknownMessageTypeError); ok {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %v", recording.Line(), err)
+		}
+		if update, ok := msg.(models.MapUpdateEvent); ok {
+			updates = append(updates, update)
+		}
+	}
+}
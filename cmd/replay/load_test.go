@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func mapUpdate(tick int) string {
+	return fmt.Sprintf(`{"type":"se.cygni.paintbot.api.event.MapUpdateEvent","gameId":"g1","gameTick":%d,"map":{"width":3,"height":3}}`, tick)
+}
+
+func TestReadMapUpdates(t *testing.T) {
+	raw := strings.Join([]string{
+		`{"type":"se.cygni.paintbot.api.event.GameStartingEvent","gameId":"g1"}`,
+		mapUpdate(0),
+		`{"type":"some.unknown.Event"}`,
+		"",
+		mapUpdate(1),
+	}, "\n")
+	recorded := strings.Join([]string{
+		`{"elapsedNs":1,"direction":"in","message":` + mapUpdate(0) + `}`,
+		`{"elapsedNs":2,"direction":"out","message":{"type":"se.cygni.paintbot.api.request.RegisterMove","gameTick":0}}`,
+		`{"elapsedNs":3,"direction":"in","message":` + mapUpdate(1) + `}`,
+	}, "\n")
+
+	for name, input := range map[string]string{"raw": raw, "recorded": recorded} {
+		updates, err := readMapUpdates(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(updates) != 2 || updates[0].GameTick != 0 || updates[1].GameTick != 1 {
+			t.Errorf("%s: expected ticks 0 and 1, got %+v", name, updates)
+		}
+	}
+}
+
+func TestReadMapUpdates_invalidLine(t *testing.T) {
+	_, err := readMapUpdates(strings.NewReader(mapUpdate(0) + "\n\n{not json"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("expected an error for line 3, got %v", err)
+	}
+}
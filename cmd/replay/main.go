@@ -0,0 +1,188 @@
+// Command replay steps through a recorded game in the terminal.
+//
+// Usage:
+//
+//	replay [-speed 250ms] [-tick N] [-play] <recording.jsonl>
+//
+// The recording is a file with one server message per line, or a recording made by basebot.Recorder.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paintbot-client/models"
+)
+
+const (
+	minSpeed = 10 * time.Millisecond
+	maxSpeed = 5 * time.Second
+)
+
+func main() {
+	speed := flag.Duration("speed", 250*time.Millisecond, "time per tick when playing")
+	tick := flag.Int("tick", 0, "game tick to start at")
+	play := flag.Bool("play", false, "start playing right away")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <recording.jsonl>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	updates, err := loadMapUpdates(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(updates) == 0 {
+		log.Fatalf("no map updates found in %s", flag.Arg(0))
+	}
+
+	p := &player{
+		out:     os.Stdout,
+		updates: updates,
+		palette: newPalette(updates),
+		speed:   *speed,
+		playing: *play,
+	}
+	p.seek(*tick)
+	p.run(readCommands(os.Stdin))
+}
+
+// player keeps track of where in the replay we are
+type player struct {
+	out     io.Writer
+	updates []models.MapUpdateEvent
+	palette *palette
+	frame   int
+	speed   time.Duration
+	playing bool
+}
+
+func (p *player) run(commands <-chan string) {
+	for {
+		p.draw()
+
+		var timer *time.Timer
+		var next <-chan time.Time
+		if p.playing {
+			timer = time.NewTimer(p.speed)
+			next = timer.C
+		}
+
+		select {
+		case cmd, ok := <-commands:
+			if timer != nil {
+				timer.Stop()
+			}
+			if !ok || !p.apply(cmd) {
+				return
+			}
+		case <-next:
+			p.step(1)
+			if p.frame == len(p.updates)-1 {
+				p.playing = false
+			}
+		}
+	}
+}
+
+// apply executes a command typed by the user and returns false if the replay should end
+func (p *player) apply(cmd string) bool {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		p.playing = false
+		p.step(1)
+		return true
+	}
+
+	switch fields[0] {
+	case "q", "quit":
+		return false
+	case "n", "next":
+		p.playing = false
+		p.step(1)
+	case "b", "back":
+		p.playing = false
+		p.step(-1)
+	case "p", "play", "pause":
+		p.playing = !p.playing
+		if p.playing && p.frame == len(p.updates)-1 {
+			p.frame = 0
+		}
+	case "g", "goto", "seek":
+		if len(fields) == 2 {
+			if tick, err := strconv.Atoi(fields[1]); err == nil {
+				p.seek(tick)
+			}
+		}
+	case "+":
+		p.speed = clampSpeed(p.speed / 2)
+	case "-":
+		p.speed = clampSpeed(p.speed * 2)
+	}
+	return true
+}
+
+func (p *player) step(frames int) {
+	p.frame += frames
+	if p.frame < 0 {
+		p.frame = 0
+	}
+	if p.frame >= len(p.updates) {
+		p.frame = len(p.updates) - 1
+	}
+}
+
+// seek moves to the first map update at or after the given game tick
+func (p *player) seek(tick int) {
+	for i, update := range p.updates {
+		if update.GameTick >= tick {
+			p.frame = i
+			return
+		}
+	}
+	p.frame = len(p.updates) - 1
+}
+
+func (p *player) draw() {
+	status := "paused"
+	if p.playing {
+		status = fmt.Sprintf("playing at %s/tick", p.speed)
+	}
+	render(p.out, p.palette, p.updates[p.frame], p.frame, len(p.updates), status)
+}
+
+func clampSpeed(speed time.Duration) time.Duration {
+	if speed < minSpeed {
+		return minSpeed
+	}
+	if speed > maxSpeed {
+		return maxSpeed
+	}
+	return speed
+}
+
+// readCommands sends every line typed by the user, the channel is closed at the end of input
+func readCommands(r io.Reader) <-chan string {
+	commands := make(chan string)
+	go func() {
+		defer close(commands)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			commands <- scanner.Text()
+		}
+	}()
+	return commands
+}
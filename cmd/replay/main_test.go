@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"paintbot-client/models"
+)
+
+func testPlayer(ticks ...int) *player {
+	p := &player{speed: 250 * time.Millisecond}
+	for _, tick := range ticks {
+		p.updates = append(p.updates, models.MapUpdateEvent{GameTick: tick})
+	}
+	return p
+}
+
+func TestPlayer_step(t *testing.T) {
+	p := testPlayer(0, 1, 2)
+	for _, tc := range []struct {
+		frames, expected int
+	}{
+		{1, 1},
+		{5, 2},
+		{-1, 1},
+		{-5, 0},
+	} {
+		p.step(tc.frames)
+		if p.frame != tc.expected {
+			t.Errorf("step(%d): expected frame %d, got %d", tc.frames, tc.expected, p.frame)
+		}
+	}
+}
+
+func TestPlayer_seek(t *testing.T) {
+	// ticks can be missing from a recording when the client skipped them
+	p := testPlayer(0, 1, 4, 5)
+	for _, tc := range []struct {
+		tick, expected int
+	}{
+		{-1, 0},
+		{1, 1},
+		{2, 2},
+		{5, 3},
+		{100, 3},
+	} {
+		p.seek(tc.tick)
+		if p.frame != tc.expected {
+			t.Errorf("seek(%d): expected frame %d, got %d", tc.tick, tc.expected, p.frame)
+		}
+	}
+}
+
+func TestPlayer_apply(t *testing.T) {
+	p := testPlayer(0, 1, 2, 3)
+	for _, tc := range []struct {
+		cmd     string
+		frame   int
+		playing bool
+	}{
+		{"", 1, false},
+		{"n", 2, false},
+		{"b", 1, false},
+		{"g 3", 3, false},
+		{"g x", 3, false},
+		{"p", 0, true}, // playing from the last frame starts over
+		{"n", 1, false},
+		{"p", 1, true},
+		{"pause", 1, false},
+	} {
+		if !p.apply(tc.cmd) {
+			t.Fatalf("%q: replay ended", tc.cmd)
+		}
+		if p.frame != tc.frame || p.playing != tc.playing {
+			t.Errorf("%q: expected frame %d playing %v, got frame %d playing %v", tc.cmd, tc.frame, tc.playing, p.frame, p.playing)
+		}
+	}
+
+	if p.apply("+"); p.speed != 125*time.Millisecond {
+		t.Errorf("expected + to halve the speed, got %s", p.speed)
+	}
+	for i := 0; i < 20; i++ {
+		p.apply("-")
+	}
+	if p.speed != maxSpeed {
+		t.Errorf("expected speed to stop at %s, got %s", maxSpeed, p.speed)
+	}
+	if p.apply("q") {
+		t.Error("expected q to end the replay")
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"paintbot-client/models"
+	"paintbot-client/utilities/maputility"
+)
+
+const (
+	reset       = "\x1b[0m"
+	clearScreen = "\x1b[H\x1b[2J"
+	bold        = "\x1b[1m"
+	obstacleBG  = "\x1b[100m"
+	openBG      = "\x1b[40m"
+	powerUpFG   = "\x1b[93m"
+	collisionFG = "\x1b[91m"
+)
+
+// background colours of the players' tiles, reused if there are more players
+var playerColours = []string{"\x1b[41m", "\x1b[42m", "\x1b[44m", "\x1b[45m", "\x1b[46m", "\x1b[43m"}
+
+// palette gives every player the same colour and label for the whole replay
+type palette struct {
+	index map[string]int
+}
+
+func newPalette(updates []models.MapUpdateEvent) *palette {
+	p := &palette{index: map[string]int{}}
+	for _, update := range updates {
+		for _, info := range update.Map.CharacterInfos {
+			if _, ok := p.index[info.ID]; !ok {
+				p.index[info.ID] = len(p.index)
+			}
+		}
+	}
+	return p
+}
+
+func (p *palette) colour(playerID string) string {
+	return playerColours[p.index[playerID]%len(playerColours)]
+}
+
+func (p *palette) label(playerID string) string {
+	return string(rune('A' + p.index[playerID]%26))
+}
+
+// render draws the map and the scoreboard of a single tick
+func render(w io.Writer, p *palette, update models.MapUpdateEvent, frame, frames int, status string) {
+	m := update.Map
+	utility := maputility.MapUtility{Map: m}
+
+	owners := map[int]string{}
+	for _, info := range m.CharacterInfos {
+		for _, pos := range info.ColouredPosition {
+			owners[pos] = info.ID
+		}
+	}
+	players := map[int]models.CharacterInfo{}
+	for _, info := range m.CharacterInfos {
+		players[info.Position] = info
+	}
+	obstacles := toSet(m.ObstacleUpPositions)
+	powerUps := toSet(m.PowerUpPositions)
+	collisions := toSet(m.CollisionInfos)
+	explosions := toSet(m.ExplosionInfos)
+
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	fmt.Fprintf(&b, "%sGame %s  tick %d  (%d/%d)%s  %s\n\n", bold, update.GameID, update.GameTick, frame+1, frames, reset, status)
+
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			pos := utility.ConvertCoordinatesToPosition(models.Coordinates{X: x, Y: y})
+			background := openBG
+			if owner, ok := owners[pos]; ok {
+				background = p.colour(owner)
+			}
+
+			cell := "  "
+			switch {
+			case obstacles[pos]:
+				background = obstacleBG
+			case hasPlayer(players, pos):
+				info := players[pos]
+				marker := " "
+				if info.StunnedForGameTicks > 0 {
+					marker = "z"
+				} else if info.CarryingPowerUp {
+					marker = "*"
+				}
+				cell = bold + p.label(info.ID) + marker
+				background = p.colour(info.ID)
+			case collisions[pos]:
+				cell = collisionFG + "!!"
+			case powerUps[pos]:
+				cell = powerUpFG + "<>"
+			case explosions[pos]:
+				cell = bold + "::"
+			}
+			b.WriteString(background + cell + reset)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	scoreboard(&b, p, m.CharacterInfos)
+	b.WriteString("\n[enter] step  [b] back  [p] play/pause  [g <tick>] seek  [+/-] speed  [q] quit\n")
+	io.WriteString(w, b.String())
+}
+
+func scoreboard(w io.Writer, p *palette, infos []models.CharacterInfo) {
+	sorted := make([]models.CharacterInfo, len(infos))
+	copy(sorted, infos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Points > sorted[j].Points
+	})
+
+	fmt.Fprintf(w, "%-3s %-20s %6s %6s %8s\n", "", "Player", "Points", "Tiles", "Stunned")
+	for _, info := range sorted {
+		fmt.Fprintf(w, "%s %s %s %-20s %6d %6d %8d\n",
+			p.colour(info.ID), p.label(info.ID), reset, info.Name, info.Points, len(info.ColouredPosition), info.StunnedForGameTicks)
+	}
+}
+
+func hasPlayer(players map[int]models.CharacterInfo, pos int) bool {
+	_, ok := players[pos]
+	return ok
+}
+
+func toSet(positions []int) map[int]bool {
+	set := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		set[pos] = true
+	}
+	return set
+}
@@ -0,0 +1,521 @@
+// Package engine simulates paintbot games locally, following the rules of the paintbot server.
+//
+// Each tick all characters act at the same time:
+//   - a stunned character does nothing, its stun wears off by one tick, after which it is
+//     invulnerable for NOOFTicksInvulnerableAfterStun ticks
+//   - a character carrying a power-up can EXPLODE, colouring every open tile within ExplosionRange
+//     (a square centred on the character) and stunning all other characters in it that are not invulnerable
+//   - moving into an obstacle, out of the map, or into another character is a collision and stuns the
+//     character moving, unless it is invulnerable
+//   - every character colours the tile it stands on and picks up the power-up there if it is not
+//     already carrying one
+//   - power-ups appear and disappear randomly according to AddPowerUpLikelihood and RemovePowerUpLikelihood,
+//     see package powerups
+//
+// Points are PointsPerTileOwned for each coloured tile plus PointsPerCausedStun for each stun caused,
+// or accumulated every tick if PointsPerTick is set.
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	"paintbot-client/models"
+	"paintbot-client/utilities/powerups"
+)
+
+// Player is a participant in a local game
+type Player struct {
+	ID   string
+	Name string
+}
+
+// PlayerStats summarises how a player is doing in a game
+type PlayerStats struct {
+	ID           string
+	Name         string
+	Points       int
+	TilesOwned   int
+	StunsCaused  int
+	TimesStunned int
+}
+
+type character struct {
+	id              string
+	name            string
+	position        int
+	stunned         int
+	invulnerable    int
+	carryingPowerUp bool
+	stunsCaused     int
+	timesStunned    int
+	points          int
+}
+
+// Game is a paintbot game played locally
+type Game struct {
+	id         string
+	settings   models.GameSettings
+	width      int
+	height     int
+	tick       int
+	obstacles  map[int]bool
+	powerUps   map[int]bool
+	characters []*character
+	// owner of each tile as an index into characters, -1 if not coloured
+	owner      []int
+	collisions []int
+	explosions []int
+	spawner    *powerups.Spawner
+}
+
+// NewGame creates a game starting from the given map, whose characters are the players of the game.
+// The seed decides where power-ups appear, so the same seed and moves always give the same game.
+func NewGame(id string, settings models.GameSettings, start models.Map, seed int64) (*Game, error) {
+	if start.Width <= 0 || start.Height <= 0 {
+		return nil, fmt.Errorf("invalid map size %dx%d", start.Width, start.Height)
+	}
+	if len(start.CharacterInfos) == 0 {
+		return nil, fmt.Errorf("no players")
+	}
+
+	g := &Game{
+		id:        id,
+		settings:  settings,
+		width:     start.Width,
+		height:    start.Height,
+		tick:      start.WorldTick,
+		obstacles: map[int]bool{},
+		powerUps:  map[int]bool{},
+		owner:     make([]int, start.Width*start.Height),
+		spawner:   powerups.NewSpawner(settings, seed),
+	}
+	for i := range g.owner {
+		g.owner[i] = -1
+	}
+
+	for _, pos := range start.ObstacleUpPositions {
+		if !g.inside(pos) {
+			return nil, fmt.Errorf("obstacle outside the map at %d", pos)
+		}
+		g.obstacles[pos] = true
+	}
+	for _, pos := range start.PowerUpPositions {
+		if !g.inside(pos) || g.obstacles[pos] {
+			return nil, fmt.Errorf("invalid power-up position %d", pos)
+		}
+		g.powerUps[pos] = true
+	}
+
+	occupied := map[int]bool{}
+	for i, info := range start.CharacterInfos {
+		if !g.inside(info.Position) || g.obstacles[info.Position] || occupied[info.Position] {
+			return nil, fmt.Errorf("invalid start position %d for %s", info.Position, info.ID)
+		}
+		occupied[info.Position] = true
+		g.characters = append(g.characters, &character{
+			id:              info.ID,
+			name:            info.Name,
+			position:        info.Position,
+			stunned:         info.StunnedForGameTicks,
+			carryingPowerUp: info.CarryingPowerUp,
+			points:          info.Points,
+		})
+		for _, pos := range info.ColouredPosition {
+			if g.inside(pos) {
+				g.owner[pos] = i
+			}
+		}
+		g.owner[info.Position] = i
+	}
+	return g, nil
+}
+
+// ID returns the game id used in the map updates
+func (g *Game) ID() string {
+	return g.id
+}
+
+// Tick returns the number of ticks played
+func (g *Game) Tick() int {
+	return g.tick
+}
+
+// Settings returns the settings the game is played with
+func (g *Game) Settings() models.GameSettings {
+	return g.settings
+}
+
+// TotalTicks returns the length of the game in ticks
+func (g *Game) TotalTicks() int {
+	if g.settings.TimeInMSPerTick <= 0 {
+		return 0
+	}
+	return g.settings.GameDurationInSeconds * 1000 / g.settings.TimeInMSPerTick
+}
+
+// Finished returns true when all ticks of the game have been played
+func (g *Game) Finished() bool {
+	return g.tick >= g.TotalTicks()
+}
+
+// PlayerIDs returns the ids of the players in the order they were given
+func (g *Game) PlayerIDs() []string {
+	ids := make([]string, len(g.characters))
+	for i, c := range g.characters {
+		ids[i] = c.id
+	}
+	return ids
+}
+
+// Step plays one tick, players without an action in actions stay where they are
+func (g *Game) Step(actions map[string]models.Action) {
+	g.collisions = nil
+	g.explosions = nil
+
+	acting := make([]bool, len(g.characters))
+	for i, c := range g.characters {
+		if c.stunned > 0 {
+			c.stunned--
+			if c.stunned == 0 {
+				c.invulnerable = g.settings.NOOFTicksInvulnerableAfterStun
+			}
+			continue
+		}
+		if c.invulnerable > 0 {
+			c.invulnerable--
+		}
+		acting[i] = true
+	}
+
+	for i, c := range g.characters {
+		if acting[i] && actions[c.id] == models.Explode && c.carryingPowerUp {
+			g.explode(i)
+		}
+	}
+
+	g.move(actions, acting)
+
+	for i, c := range g.characters {
+		g.owner[c.position] = i
+		if g.powerUps[c.position] && !c.carryingPowerUp {
+			c.carryingPowerUp = true
+			delete(g.powerUps, c.position)
+		}
+	}
+
+	g.updatePowerUps()
+	g.tick++
+	g.score()
+}
+
+// explode colours the tiles around character i and stuns the characters there
+func (g *Game) explode(i int) {
+	c := g.characters[i]
+	c.carryingPowerUp = false
+	r := g.settings.ExplosionRange
+	cx, cy := c.position%g.width, c.position/g.width
+
+	for y := cy - r; y <= cy+r; y++ {
+		for x := cx - r; x <= cx+r; x++ {
+			if x < 0 || y < 0 || x >= g.width || y >= g.height {
+				continue
+			}
+			pos := y*g.width + x
+			if g.obstacles[pos] {
+				continue
+			}
+			g.owner[pos] = i
+			g.explosions = append(g.explosions, pos)
+		}
+	}
+
+	for j, other := range g.characters {
+		if j == i {
+			continue
+		}
+		ox, oy := other.position%g.width, other.position/g.width
+		if abs(ox-cx) <= r && abs(oy-cy) <= r && g.stun(other) {
+			c.stunsCaused++
+		}
+	}
+}
+
+// stun returns true if the character was stunned
+func (g *Game) stun(c *character) bool {
+	if c.invulnerable > 0 || c.stunned > 0 {
+		return false
+	}
+	c.stunned = g.settings.NOOFTicksStunned
+	c.timesStunned++
+	return true
+}
+
+// move moves all acting characters at the same time, characters that collide are stunned and stay
+func (g *Game) move(actions map[string]models.Action, acting []bool) {
+	targets := make([]int, len(g.characters))
+	moving := make([]bool, len(g.characters))
+	collided := make([]bool, len(g.characters))
+
+	for i, c := range g.characters {
+		targets[i] = c.position
+		if !acting[i] || c.stunned > 0 {
+			continue
+		}
+		target, ok := g.target(c.position, actions[c.id])
+		if target == c.position && ok {
+			continue
+		}
+		if !ok || g.obstacles[target] {
+			collided[i] = true
+			g.collisions = append(g.collisions, c.position)
+			continue
+		}
+		targets[i] = target
+		moving[i] = true
+	}
+
+	// characters moving to the same tile or swapping tiles all fail
+	contested := make([]bool, len(g.characters))
+	for i := range g.characters {
+		for j := range g.characters {
+			if i == j || !moving[i] || !moving[j] {
+				continue
+			}
+			swapping := targets[j] == g.characters[i].position && targets[i] == g.characters[j].position
+			if targets[i] == targets[j] || swapping {
+				contested[i] = true
+			}
+		}
+	}
+	for i, c := range contested {
+		if c {
+			moving[i] = false
+			collided[i] = true
+			g.collisions = append(g.collisions, targets[i])
+			targets[i] = g.characters[i].position
+		}
+	}
+
+	// a move fails if another character stays on the target tile,
+	// which can make further moves fail so repeat until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for i := range g.characters {
+			if !moving[i] {
+				continue
+			}
+			for j := range g.characters {
+				if i != j && !moving[j] && targets[j] == targets[i] {
+					moving[i] = false
+					collided[i] = true
+					targets[i] = g.characters[i].position
+					g.collisions = append(g.collisions, targets[j])
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for i, c := range g.characters {
+		c.position = targets[i]
+		if collided[i] {
+			g.stun(c)
+		}
+	}
+	g.collisions = uniqueSorted(g.collisions)
+}
+
+// target returns where an action leads from pos, and false if it leads out of the map
+func (g *Game) target(pos int, action models.Action) (int, bool) {
+	x, y := pos%g.width, pos/g.width
+	switch action {
+	case models.Left:
+		x--
+	case models.Right:
+		x++
+	case models.Up:
+		y--
+	case models.Down:
+		y++
+	}
+	if x < 0 || y < 0 || x >= g.width || y >= g.height {
+		return pos, false
+	}
+	return y*g.width + x, true
+}
+
+// updatePowerUps randomly adds and removes power-ups
+func (g *Game) updatePowerUps() {
+	powerUps := g.spawner.Step(sortedKeys(g.powerUps), g.freeTiles())
+	g.powerUps = map[int]bool{}
+	for _, pos := range powerUps {
+		g.powerUps[pos] = true
+	}
+}
+
+// freeTiles returns the tiles without obstacles, power-ups or characters
+func (g *Game) freeTiles() []int {
+	occupied := map[int]bool{}
+	for _, c := range g.characters {
+		occupied[c.position] = true
+	}
+	var free []int
+	for pos := 0; pos < g.width*g.height; pos++ {
+		if !g.obstacles[pos] && !g.powerUps[pos] && !occupied[pos] {
+			free = append(free, pos)
+		}
+	}
+	return free
+}
+
+func (g *Game) score() {
+	owned := g.tilesOwned()
+	for i, c := range g.characters {
+		tilePoints := owned[i] * g.settings.PointsPerTileOwned
+		stunPoints := c.stunsCaused * g.settings.PointsPerCausedStun
+		if g.settings.PointsPerTick {
+			c.points += tilePoints
+		} else {
+			c.points = tilePoints + stunPoints
+		}
+	}
+}
+
+func (g *Game) tilesOwned() []int {
+	owned := make([]int, len(g.characters))
+	for _, i := range g.owner {
+		if i >= 0 {
+			owned[i]++
+		}
+	}
+	return owned
+}
+
+// Map returns the current state of the game in the same form as the server sends it
+func (g *Game) Map() models.Map {
+	coloured := make([][]int, len(g.characters))
+	for pos, i := range g.owner {
+		if i >= 0 {
+			coloured[i] = append(coloured[i], pos)
+		}
+	}
+
+	infos := make([]models.CharacterInfo, len(g.characters))
+	for i, c := range g.characters {
+		positions := coloured[i]
+		if positions == nil {
+			positions = []int{}
+		}
+		infos[i] = models.CharacterInfo{
+			Name:                c.name,
+			Points:              g.points(c),
+			Position:            c.position,
+			ColouredPosition:    positions,
+			StunnedForGameTicks: c.stunned,
+			ID:                  c.id,
+			CarryingPowerUp:     c.carryingPowerUp,
+		}
+	}
+
+	return models.Map{
+		Width:               g.width,
+		Height:              g.height,
+		WorldTick:           g.tick,
+		CharacterInfos:      infos,
+		PowerUpPositions:    sortedKeys(g.powerUps),
+		ObstacleUpPositions: sortedKeys(g.obstacles),
+		CollisionInfos:      append([]int{}, g.collisions...),
+		ExplosionInfos:      uniqueSorted(g.explosions),
+	}
+}
+
+// points includes the stun points when they are not added every tick
+func (g *Game) points(c *character) int {
+	if g.settings.PointsPerTick {
+		return c.points + c.stunsCaused*g.settings.PointsPerCausedStun
+	}
+	return c.points
+}
+
+// MapUpdate returns the map update the server would send to the given player
+func (g *Game) MapUpdate(playerID string) models.MapUpdateEvent {
+	id := playerID
+	return models.MapUpdateEvent{
+		Type:              models.TypeMapUpdate,
+		GameID:            g.id,
+		GameTick:          g.tick,
+		Map:               g.Map(),
+		ReceivingPlayerID: &id,
+	}
+}
+
+// Stats returns how each player is doing, in the order the players were given
+func (g *Game) Stats() []PlayerStats {
+	owned := g.tilesOwned()
+	stats := make([]PlayerStats, len(g.characters))
+	for i, c := range g.characters {
+		stats[i] = PlayerStats{
+			ID:           c.id,
+			Name:         c.name,
+			Points:       g.points(c),
+			TilesOwned:   owned[i],
+			StunsCaused:  c.stunsCaused,
+			TimesStunned: c.timesStunned,
+		}
+	}
+	return stats
+}
+
+// Ranks returns the players ordered by points, players with equal points share rank
+func (g *Game) Ranks() []models.PlayerRank {
+	stats := g.Stats()
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].Points > stats[j].Points
+	})
+
+	ranks := make([]models.PlayerRank, len(stats))
+	for i, s := range stats {
+		rank := i + 1
+		if i > 0 && s.Points == stats[i-1].Points {
+			rank = ranks[i-1].Rank
+		}
+		ranks[i] = models.PlayerRank{
+			PlayerName: s.Name,
+			PlayerId:   s.ID,
+			Rank:       rank,
+			Points:     s.Points,
+			Alive:      true,
+		}
+	}
+	return ranks
+}
+
+func (g *Game) inside(pos int) bool {
+	return pos >= 0 && pos < g.width*g.height
+}
+
+func sortedKeys(set map[int]bool) []int {
+	keys := make([]int, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func uniqueSorted(positions []int) []int {
+	set := map[int]bool{}
+	for _, pos := range positions {
+		set[pos] = true
+	}
+	return sortedKeys(set)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
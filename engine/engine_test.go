@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"paintbot-client/mapgen"
+	"paintbot-client/models"
+)
+
+var settings = models.GameSettings{
+	MaxNOOFPlayers:                 5,
+	TimeInMSPerTick:                250,
+	PointsPerTileOwned:             1,
+	PointsPerCausedStun:            5,
+	NOOFTicksInvulnerableAfterStun: 3,
+	NOOFTicksStunned:               10,
+	GameDurationInSeconds:          10,
+	ExplosionRange:                 1,
+}
+
+// newGame creates a game on a 5x5 map with a player at each of the given positions, named p0, p1...
+func newGame(t *testing.T, positions ...int) *Game {
+	m := models.Map{Width: 5, Height: 5}
+	for i, pos := range positions {
+		id := string(rune('0' + i))
+		m.CharacterInfos = append(m.CharacterInfos, models.CharacterInfo{ID: "p" + id, Name: "player" + id, Position: pos})
+	}
+	g, err := NewGame("game", settings, m, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func characterInfo(g *Game, id string) models.CharacterInfo {
+	for _, c := range g.Map().CharacterInfos {
+		if c.ID == id {
+			return c
+		}
+	}
+	return models.CharacterInfo{}
+}
+
+func TestGame_moveColours(t *testing.T) {
+	g := newGame(t, 0)
+	g.Step(map[string]models.Action{"p0": models.Right})
+	g.Step(map[string]models.Action{"p0": models.Down})
+
+	me := characterInfo(g, "p0")
+	if me.Position != 6 || !reflect.DeepEqual(me.ColouredPosition, []int{0, 1, 6}) {
+		t.Errorf("unexpected character %+v", me)
+	}
+	if me.Points != 3 || g.Tick() != 2 {
+		t.Errorf("expected 3 points at tick 2, got %d at tick %d", me.Points, g.Tick())
+	}
+}
+
+func TestGame_collisionStuns(t *testing.T) {
+	g := newGame(t, 0)
+	g.obstacles[1] = true
+
+	g.Step(map[string]models.Action{"p0": models.Right})
+	me := characterInfo(g, "p0")
+	if me.Position != 0 || me.StunnedForGameTicks != settings.NOOFTicksStunned {
+		t.Errorf("expected stun at 0, got %+v", me)
+	}
+	if !reflect.DeepEqual(g.Map().CollisionInfos, []int{0}) {
+		t.Errorf("unexpected collisions %v", g.Map().CollisionInfos)
+	}
+
+	for i := 0; i < settings.NOOFTicksStunned; i++ {
+		g.Step(map[string]models.Action{"p0": models.Down})
+	}
+	if characterInfo(g, "p0").Position != 0 {
+		t.Error("stunned character moved")
+	}
+	// invulnerable after the stun, so walking out of the map does not stun again
+	g.Step(map[string]models.Action{"p0": models.Up})
+	if characterInfo(g, "p0").StunnedForGameTicks != 0 {
+		t.Error("invulnerable character stunned")
+	}
+}
+
+func TestGame_playersCollide(t *testing.T) {
+	g := newGame(t, 0, 2)
+	g.Step(map[string]models.Action{"p0": models.Right, "p1": models.Left})
+	if characterInfo(g, "p0").Position != 0 || characterInfo(g, "p1").Position != 2 {
+		t.Error("both players moved to the same tile")
+	}
+	if characterInfo(g, "p0").StunnedForGameTicks == 0 || characterInfo(g, "p1").StunnedForGameTicks == 0 {
+		t.Error("expected both players to be stunned")
+	}
+
+	// following a player moving away is fine
+	g = newGame(t, 0, 1)
+	g.Step(map[string]models.Action{"p0": models.Right, "p1": models.Right})
+	if characterInfo(g, "p0").Position != 1 || characterInfo(g, "p1").Position != 2 {
+		t.Error("expected both players to move")
+	}
+}
+
+func TestGame_powerUpAndExplosion(t *testing.T) {
+	g := newGame(t, 6, 8, 24)
+	g.powerUps[7] = true
+
+	g.Step(map[string]models.Action{"p0": models.Right})
+	if !characterInfo(g, "p0").CarryingPowerUp || len(g.Map().PowerUpPositions) != 0 {
+		t.Fatal("power-up not picked up")
+	}
+
+	g.Step(map[string]models.Action{"p0": models.Explode})
+	if characterInfo(g, "p0").CarryingPowerUp {
+		t.Error("power-up still carried after explosion")
+	}
+	if characterInfo(g, "p1").StunnedForGameTicks != settings.NOOFTicksStunned {
+		t.Error("player in range not stunned")
+	}
+	if characterInfo(g, "p2").StunnedForGameTicks != 0 {
+		t.Error("player out of range stunned")
+	}
+	if len(g.Map().ExplosionInfos) != 9 {
+		t.Errorf("expected 9 exploded tiles, got %v", g.Map().ExplosionInfos)
+	}
+
+	stats := g.Stats()[0]
+	if stats.StunsCaused != 1 || stats.Points != stats.TilesOwned+settings.PointsPerCausedStun {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if g.Ranks()[0].PlayerId != "p0" {
+		t.Errorf("expected p0 to lead, got %+v", g.Ranks())
+	}
+}
+
+func TestGame_finished(t *testing.T) {
+	g := newGame(t, 0)
+	for !g.Finished() {
+		g.Step(nil)
+	}
+	if g.Tick() != 40 {
+		t.Errorf("expected 40 ticks, got %d", g.Tick())
+	}
+}
+
+func TestNew_sameSeedSameGame(t *testing.T) {
+	s := settings
+	s.ObstaclesEnabled = true
+	s.StartObstacles = 5
+	s.PowerUpsEnabled = true
+	s.StartPowerUps = 2
+	s.AddPowerUpLikelihood = 50
+	s.RemovePowerUpLikelihood = 20
+	players := []Player{{ID: "a", Name: "a"}, {ID: "b", Name: "b"}}
+
+	play := func() models.Map {
+		layout, err := mapgen.Generate(42, 10, 10, s, len(players))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := New("game", s, layout, players, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 20; i++ {
+			g.Step(map[string]models.Action{"a": models.Right, "b": models.Down})
+		}
+		return g.Map()
+	}
+	if !reflect.DeepEqual(play(), play()) {
+		t.Error("same seed gave different games")
+	}
+}
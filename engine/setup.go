@@ -0,0 +1,19 @@
+package engine
+
+import (
+	"paintbot-client/mapgen"
+	"paintbot-client/models"
+)
+
+// New creates a game for the players, placed on the spawn points of the layout in the order given
+func New(id string, settings models.GameSettings, layout mapgen.Layout, players []Player, seed int64) (*Game, error) {
+	characters := make([]models.CharacterInfo, len(players))
+	for i, p := range players {
+		characters[i] = models.CharacterInfo{ID: p.ID, Name: p.Name}
+	}
+	start, err := layout.Map(characters)
+	if err != nil {
+		return nil, err
+	}
+	return NewGame(id, settings, start, seed)
+}
@@ -0,0 +1,79 @@
+package mapgen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Load reads a fixed layout, one line per row of the map, with
+//   - '#' for an obstacle
+//   - '*' for a power-up
+//   - '.' for an open tile
+//   - '0' to '9' for spawn points, given out in that order
+//
+// All rows must be equally long, empty lines are ignored.
+func Load(r io.Reader) (Layout, error) {
+	var l Layout
+	spawns := map[rune]int{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		row := strings.TrimRight(scanner.Text(), " \t\r")
+		if row == "" {
+			continue
+		}
+		if l.Width == 0 {
+			l.Width = len(row)
+		} else if len(row) != l.Width {
+			return Layout{}, fmt.Errorf("line %d: row is %d tiles wide, expected %d", line, len(row), l.Width)
+		}
+
+		for x, tile := range row {
+			pos := l.Height*l.Width + x
+			switch {
+			case tile == '#':
+				l.Obstacles = append(l.Obstacles, pos)
+			case tile == '*':
+				l.PowerUps = append(l.PowerUps, pos)
+			case tile == '.':
+			case tile >= '0' && tile <= '9':
+				if _, ok := spawns[tile]; ok {
+					return Layout{}, fmt.Errorf("line %d: spawn point %c given twice", line, tile)
+				}
+				spawns[tile] = pos
+			default:
+				return Layout{}, fmt.Errorf("line %d: unknown tile %q", line, tile)
+			}
+		}
+		l.Height++
+	}
+	if err := scanner.Err(); err != nil {
+		return Layout{}, err
+	}
+	if l.Height == 0 {
+		return Layout{}, fmt.Errorf("empty map")
+	}
+
+	var order []rune
+	for tile := range spawns {
+		order = append(order, tile)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+	for _, tile := range order {
+		l.Spawns = append(l.Spawns, spawns[tile])
+	}
+	return l, nil
+}
+
+// LoadFile reads a fixed layout from a file, see Load for the format
+func LoadFile(path string) (Layout, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Layout{}, err
+	}
+	defer f.Close()
+	return Load(f)
+}
@@ -0,0 +1,205 @@
+// Package mapgen lays out maps for local games, either generated from a seed or loaded from a text file
+package mapgen
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"paintbot-client/models"
+)
+
+// Layout is a map before any players have joined
+type Layout struct {
+	Width     int
+	Height    int
+	Obstacles []int
+	PowerUps  []int
+	// Spawns are the start positions of the players, in the order they are given out
+	Spawns []int
+}
+
+// Generate lays out StartObstacles obstacles and StartPowerUps power-ups, if enabled in settings,
+// and spawn points for players spread out over the map. All open tiles can be reached from each other.
+// The same arguments always give the same layout.
+func Generate(seed int64, width, height int, settings models.GameSettings, players int) (Layout, error) {
+	obstacles := 0
+	if settings.ObstaclesEnabled {
+		obstacles = settings.StartObstacles
+	}
+	powerUps := 0
+	if settings.PowerUpsEnabled {
+		powerUps = settings.StartPowerUps
+	}
+	if width <= 0 || height <= 0 {
+		return Layout{}, fmt.Errorf("invalid map size %dx%d", width, height)
+	}
+	if obstacles+powerUps+players > width*height {
+		return Layout{}, fmt.Errorf("%dx%d map too small for %d obstacles, %d power-ups and %d players",
+			width, height, obstacles, powerUps, players)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	l := Layout{Width: width, Height: height}
+	blocked := make([]bool, width*height)
+
+	// obstacles that would cut off part of the map are skipped, and tried again after more obstacles
+	// have been placed until no more fit
+	for placed := true; placed && len(l.Obstacles) < obstacles; {
+		placed = false
+		for _, pos := range rng.Perm(width * height) {
+			if len(l.Obstacles) == obstacles {
+				break
+			}
+			if blocked[pos] {
+				continue
+			}
+			blocked[pos] = true
+			if l.connected(blocked) {
+				l.Obstacles = append(l.Obstacles, pos)
+				placed = true
+			} else {
+				blocked[pos] = false
+			}
+		}
+	}
+	if len(l.Obstacles) < obstacles {
+		return Layout{}, fmt.Errorf("could not place %d obstacles without cutting off part of the map", obstacles)
+	}
+
+	l.Spawns = l.spreadOut(rng, blocked, players)
+	for _, pos := range l.Spawns {
+		blocked[pos] = true
+	}
+
+	for _, pos := range rng.Perm(width * height) {
+		if len(l.PowerUps) == powerUps {
+			break
+		}
+		if !blocked[pos] {
+			l.PowerUps = append(l.PowerUps, pos)
+		}
+	}
+
+	sort.Ints(l.Obstacles)
+	sort.Ints(l.PowerUps)
+	return l, nil
+}
+
+// connected returns true if all tiles that are not blocked can be reached from each other
+func (l Layout) connected(blocked []bool) bool {
+	open := 0
+	start := -1
+	for pos, b := range blocked {
+		if !b {
+			open++
+			start = pos
+		}
+	}
+	if open == 0 {
+		return true
+	}
+
+	seen := make([]bool, len(blocked))
+	seen[start] = true
+	queue := []int{start}
+	reached := 0
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		reached++
+		for _, next := range l.neighbours(pos) {
+			if !blocked[next] && !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return reached == open
+}
+
+func (l Layout) neighbours(pos int) []int {
+	x, y := pos%l.Width, pos/l.Width
+	var n []int
+	if x > 0 {
+		n = append(n, pos-1)
+	}
+	if x < l.Width-1 {
+		n = append(n, pos+1)
+	}
+	if y > 0 {
+		n = append(n, pos-l.Width)
+	}
+	if y < l.Height-1 {
+		n = append(n, pos+l.Width)
+	}
+	return n
+}
+
+// spreadOut picks a random open tile and then, one at a time, the open tile furthest from those already picked
+func (l Layout) spreadOut(rng *rand.Rand, blocked []bool, n int) []int {
+	var open []int
+	for pos, b := range blocked {
+		if !b {
+			open = append(open, pos)
+		}
+	}
+	if n == 0 || len(open) == 0 {
+		return nil
+	}
+
+	picked := []int{open[rng.Intn(len(open))]}
+	for len(picked) < n {
+		best, bestDistance := -1, -1
+		for _, pos := range open {
+			distance := -1
+			for _, p := range picked {
+				if d := l.distance(pos, p); distance < 0 || d < distance {
+					distance = d
+				}
+			}
+			if distance > bestDistance {
+				best, bestDistance = pos, distance
+			}
+		}
+		picked = append(picked, best)
+	}
+	return picked
+}
+
+func (l Layout) distance(a, b int) int {
+	dx, dy := a%l.Width-b%l.Width, a/l.Width-b/l.Width
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx + dy
+}
+
+// Map places the characters on the spawn points in order, only their ID and Name are used
+func (l Layout) Map(characters []models.CharacterInfo) (models.Map, error) {
+	if len(characters) > len(l.Spawns) {
+		return models.Map{}, fmt.Errorf("%d players but only %d spawn points", len(characters), len(l.Spawns))
+	}
+
+	m := models.Map{
+		Width:               l.Width,
+		Height:              l.Height,
+		PowerUpPositions:    append([]int{}, l.PowerUps...),
+		ObstacleUpPositions: append([]int{}, l.Obstacles...),
+		CollisionInfos:      []int{},
+		ExplosionInfos:      []int{},
+	}
+	for i, c := range characters {
+		pos := l.Spawns[i]
+		m.CharacterInfos = append(m.CharacterInfos, models.CharacterInfo{
+			Name:             c.Name,
+			ID:               c.ID,
+			Position:         pos,
+			ColouredPosition: []int{pos},
+		})
+	}
+	return m, nil
+}
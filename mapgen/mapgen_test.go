@@ -0,0 +1,103 @@
+package mapgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"paintbot-client/models"
+)
+
+var settings = models.GameSettings{
+	ObstaclesEnabled: true,
+	PowerUpsEnabled:  true,
+	StartObstacles:   60,
+	StartPowerUps:    20,
+}
+
+func TestGenerate_sameSeedSameMap(t *testing.T) {
+	a, err := Generate(7, 20, 15, settings, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Generate(7, 20, 15, settings, 4)
+	if !reflect.DeepEqual(a, b) {
+		t.Error("same seed gave different layouts")
+	}
+	c, _ := Generate(8, 20, 15, settings, 4)
+	if reflect.DeepEqual(a, c) {
+		t.Error("different seeds gave the same layout")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		l, err := Generate(seed, 10, 10, settings, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(l.Obstacles) != 60 || len(l.PowerUps) != 20 || len(l.Spawns) != 5 {
+			t.Fatalf("unexpected layout %+v", l)
+		}
+
+		blocked := make([]bool, 100)
+		for _, pos := range l.Obstacles {
+			blocked[pos] = true
+		}
+		if !l.connected(blocked) {
+			t.Errorf("seed %d: open tiles not connected", seed)
+		}
+		taken := map[int]bool{}
+		for _, pos := range append(append(append([]int{}, l.Obstacles...), l.PowerUps...), l.Spawns...) {
+			if taken[pos] {
+				t.Errorf("seed %d: tile %d used twice", seed, pos)
+			}
+			taken[pos] = true
+		}
+	}
+}
+
+func TestGenerate_disabled(t *testing.T) {
+	l, err := Generate(1, 5, 5, models.GameSettings{StartObstacles: 10, StartPowerUps: 10}, 2)
+	if err != nil || len(l.Obstacles) != 0 || len(l.PowerUps) != 0 {
+		t.Errorf("expected no obstacles or power-ups, got %+v (%v)", l, err)
+	}
+	// spawn points are as far apart as possible
+	if l.distance(l.Spawns[0], l.Spawns[1]) < 4 {
+		t.Errorf("spawn points too close %v", l.Spawns)
+	}
+	if _, err := Generate(1, 2, 2, settings, 2); err == nil {
+		t.Error("expected too small map to fail")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	l, err := Load(strings.NewReader("1.#\n\n*#0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Layout{Width: 3, Height: 2, Obstacles: []int{2, 4}, PowerUps: []int{3}, Spawns: []int{5, 0}}
+	if !reflect.DeepEqual(l, expected) {
+		t.Errorf("expected %+v, got %+v", expected, l)
+	}
+
+	for _, bad := range []string{"", "..\n...", ".x.", "0.0"} {
+		if _, err := Load(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q should not load", bad)
+		}
+	}
+}
+
+func TestLayout_Map(t *testing.T) {
+	l := Layout{Width: 3, Height: 1, PowerUps: []int{1}, Spawns: []int{2, 0}}
+	m, err := l.Map([]models.CharacterInfo{{ID: "a", Name: "A"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.CharacterInfos) != 1 || m.CharacterInfos[0].Position != 2 || m.CharacterInfos[0].Name != "A" {
+		t.Errorf("unexpected characters %+v", m.CharacterInfos)
+	}
+	if _, err := l.Map(make([]models.CharacterInfo, 3)); err == nil {
+		t.Error("expected too many players to fail")
+	}
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Action string
 
 const (
@@ -20,9 +25,58 @@ const (
 	Open     Tile = "OPEN"
 )
 
+// GameMode is the path on the server used to join a game
 type GameMode string
 
 const (
 	Tournament GameMode = "/tournament"
 	Training   GameMode = "/training"
+
+	arenaPath = "/arena"
 )
+
+// Arena returns the game mode for joining the named arena, where several teams play against each other
+func Arena(name string) GameMode {
+	return GameMode(arenaPath + "/" + name)
+}
+
+// CustomGameMode returns a game mode for any path on the server
+func CustomGameMode(path string) GameMode {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return GameMode(path)
+}
+
+// ParseGameMode parses "training", "tournament", "arena:<name>" or a path starting with "/"
+func ParseGameMode(s string) (GameMode, error) {
+	switch {
+	case s == "training":
+		return Training, nil
+	case s == "tournament":
+		return Tournament, nil
+	case strings.HasPrefix(s, "arena:"):
+		name := strings.TrimPrefix(s, "arena:")
+		if name == "" {
+			return "", fmt.Errorf("missing arena name in %q", s)
+		}
+		return Arena(name), nil
+	case strings.HasPrefix(s, "/"):
+		return CustomGameMode(s), nil
+	default:
+		return "", fmt.Errorf("unknown game mode %q, expected training, tournament, arena:<name> or a path", s)
+	}
+}
+
+// IsArena returns true if the game mode joins an arena
+func (m GameMode) IsArena() bool {
+	return strings.HasPrefix(string(m), arenaPath+"/")
+}
+
+// ArenaName returns the name of the arena, or "" if the game mode is not an arena
+func (m GameMode) ArenaName() string {
+	if !m.IsArena() {
+		return ""
+	}
+	return strings.TrimPrefix(string(m), arenaPath+"/")
+}
@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestParseGameMode(t *testing.T) {
+	cases := map[string]GameMode{
+		"training":     Training,
+		"tournament":   Tournament,
+		"arena:team42": "/arena/team42",
+		"/custom/path": "/custom/path",
+	}
+	for s, expected := range cases {
+		mode, err := ParseGameMode(s)
+		if err != nil || mode != expected {
+			t.Errorf("%s: expected %s, got %s (%v)", s, expected, mode, err)
+		}
+	}
+
+	for _, s := range []string{"", "arena:", "practice"} {
+		if _, err := ParseGameMode(s); err == nil {
+			t.Errorf("%q should not parse", s)
+		}
+	}
+}
+
+func TestGameMode_arena(t *testing.T) {
+	mode := Arena("private")
+	if !mode.IsArena() || mode.ArenaName() != "private" {
+		t.Fail()
+	}
+	if Training.IsArena() || Training.ArenaName() != "" {
+		t.Fail()
+	}
+	if CustomGameMode("arena2") != "/arena2" || CustomGameMode("arena2").IsArena() {
+		t.Fail()
+	}
+}
@@ -176,3 +176,9 @@ type HearbeatMessage struct {
 	ReceivingPlayerID *string `json:"receivingPlayerId"`
 	Timestamp         int     `json:"timestamp"`
 }
+
+type HeartBeatResponse struct {
+	Type              string  `json:"type"`
+	ReceivingPlayerID *string `json:"receivingPlayerId"`
+	Timestamp         int     `json:"timestamp"`
+}
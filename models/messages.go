@@ -0,0 +1,112 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Message types of the paintbot protocol
+const (
+	TypeRegisterPlayer    = "se.cygni.paintbot.api.request.RegisterPlayer"
+	TypeStartGame         = "se.cygni.paintbot.api.request.StartGame"
+	TypeClientInfo        = "se.cygni.paintbot.api.request.ClientInfo"
+	TypeRegisterMove      = "se.cygni.paintbot.api.request.RegisterMove"
+	TypeHeartBeatRequest  = "se.cygni.paintbot.api.request.HeartBeatRequest"
+	TypeHeartBeatResponse = "se.cygni.paintbot.api.response.HeartBeatResponse"
+	TypePlayerRegistered  = "se.cygni.paintbot.api.response.PlayerRegistered"
+	TypeGameLink          = "se.cygni.paintbot.api.event.GameLinkEvent"
+	TypeGameStarting      = "se.cygni.paintbot.api.event.GameStartingEvent"
+	TypeMapUpdate         = "se.cygni.paintbot.api.event.MapUpdateEvent"
+	TypeGameResult        = "se.cygni.paintbot.api.event.GameResultEvent"
+	TypeGameEnded         = "se.cygni.paintbot.api.event.GameEndedEvent"
+	TypeTournamentEnded   = "se.cygni.paintbot.api.event.TournamentEndedEvent"
+	TypeInvalidMessage    = "se.cygni.paintbot.api.exception.InvalidMessage"
+)
+
+// Message is implemented by all messages sent between the client and the server
+type Message interface {
+	MessageType() string
+}
+
+// UnknownMessageTypeError is returned by Decode for messages whose type is not registered
+type UnknownMessageTypeError struct {
+	Type string
+}
+
+func (e *UnknownMessageTypeError) Error() string {
+	return fmt.Sprintf("unknown message type %q", e.Type)
+}
+
+var (
+	registryMux sync.RWMutex
+	registry    = map[string]reflect.Type{}
+)
+
+func init() {
+	RegisterMessage(RegisterPlayerEvent{})
+	RegisterMessage(StartGameEvent{})
+	RegisterMessage(ClientInfoMSG{})
+	RegisterMessage(RegisterMoveEvent{})
+	RegisterMessage(HearbeatMessage{})
+	RegisterMessage(HeartBeatResponse{})
+	RegisterMessage(PlayerRegisteredEvent{})
+	RegisterMessage(GameLinkEvent{})
+	RegisterMessage(GameStartingEvent{})
+	RegisterMessage(MapUpdateEvent{})
+	RegisterMessage(GameResultEvent{})
+	RegisterMessage(GameEndedEvent{})
+	RegisterMessage(TournamentEndedEvent{})
+	RegisterMessage(InvalidMessage{})
+}
+
+// RegisterMessage makes Decode return messages of prototype's type for its MessageType.
+// prototype must be a struct value, not a pointer.
+func RegisterMessage(prototype Message) {
+	t := reflect.TypeOf(prototype)
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("message prototype must be a struct, got %s", t))
+	}
+	registryMux.Lock()
+	defer registryMux.Unlock()
+	registry[prototype.MessageType()] = t
+}
+
+// Decode parses a message and returns it as the registered struct for its type, e.g. a MapUpdateEvent.
+// An *UnknownMessageTypeError is returned for types that are not registered.
+func Decode(data []byte) (Message, error) {
+	header := GameMessage{}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	registryMux.RLock()
+	t, ok := registry[header.Type]
+	registryMux.RUnlock()
+	if !ok {
+		return nil, &UnknownMessageTypeError{Type: header.Type}
+	}
+
+	msg := reflect.New(t)
+	if err := json.Unmarshal(data, msg.Interface()); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", header.Type, err)
+	}
+	return msg.Elem().Interface().(Message), nil
+}
+
+func (m GameMessage) MessageType() string           { return m.Type }
+func (m RegisterPlayerEvent) MessageType() string   { return TypeRegisterPlayer }
+func (m StartGameEvent) MessageType() string        { return TypeStartGame }
+func (m ClientInfoMSG) MessageType() string         { return TypeClientInfo }
+func (m RegisterMoveEvent) MessageType() string     { return TypeRegisterMove }
+func (m HearbeatMessage) MessageType() string       { return TypeHeartBeatRequest }
+func (m HeartBeatResponse) MessageType() string     { return TypeHeartBeatResponse }
+func (m PlayerRegisteredEvent) MessageType() string { return TypePlayerRegistered }
+func (m GameLinkEvent) MessageType() string         { return TypeGameLink }
+func (m GameStartingEvent) MessageType() string     { return TypeGameStarting }
+func (m MapUpdateEvent) MessageType() string        { return TypeMapUpdate }
+func (m GameResultEvent) MessageType() string       { return TypeGameResult }
+func (m GameEndedEvent) MessageType() string        { return TypeGameEnded }
+func (m TournamentEndedEvent) MessageType() string  { return TypeTournamentEnded }
+func (m InvalidMessage) MessageType() string        { return TypeInvalidMessage }
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecode_mapUpdate(t *testing.T) {
+	raw := []byte(`{"type":"se.cygni.paintbot.api.event.MapUpdateEvent","gameId":"g1","gameTick":7,
+		"map":{"width":3,"height":2,"characterInfos":[{"id":"p1","position":4}]},"receivingPlayerId":"p1","timestamp":10}`)
+
+	msg, err := Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, ok := msg.(MapUpdateEvent)
+	if !ok {
+		t.Fatalf("expected MapUpdateEvent, got %T", msg)
+	}
+	if event.GameID != "g1" || event.GameTick != 7 || event.Map.Width != 3 || event.Map.CharacterInfos[0].Position != 4 {
+		t.Errorf("unexpected event: %+v", event)
+	}
+}
+
+func TestDecode_unknownType(t *testing.T) {
+	_, err := Decode([]byte(`{"type":"se.cygni.paintbot.api.event.SomethingNew"}`))
+	unknown, ok := err.(*UnknownMessageTypeError)
+	if !ok {
+		t.Fatalf("expected UnknownMessageTypeError, got %v", err)
+	}
+	if unknown.Type != "se.cygni.paintbot.api.event.SomethingNew" {
+		t.Fail()
+	}
+}
+
+func TestDecode_invalidJSON(t *testing.T) {
+	if _, err := Decode([]byte(`{"type":`)); err == nil {
+		t.Fail()
+	}
+}
+
+func TestDecode_roundTrip(t *testing.T) {
+	messages := []Message{
+		RegisterPlayerEvent{Type: TypeRegisterPlayer, PlayerName: "bot"},
+		StartGameEvent{Type: TypeStartGame},
+		ClientInfoMSG{Type: TypeClientInfo, Language: "Go"},
+		RegisterMoveEvent{Type: TypeRegisterMove, GameTick: 3, Action: string(Left)},
+		HearbeatMessage{Type: TypeHeartBeatRequest},
+		HeartBeatResponse{Type: TypeHeartBeatResponse},
+		PlayerRegisteredEvent{Type: TypePlayerRegistered, GameID: "g"},
+		GameLinkEvent{Type: TypeGameLink, URL: "http://x"},
+		GameStartingEvent{Type: TypeGameStarting, Width: 10},
+		MapUpdateEvent{Type: TypeMapUpdate, GameTick: 1},
+		GameResultEvent{Type: TypeGameResult},
+		GameEndedEvent{Type: TypeGameEnded, PlayerWinnerID: "p"},
+		TournamentEndedEvent{Type: TypeTournamentEnded},
+		InvalidMessage{Type: TypeInvalidMessage, ErrorMessage: "bad"},
+	}
+
+	for _, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := Decode(data)
+		if err != nil {
+			t.Errorf("%s: %v", msg.MessageType(), err)
+			continue
+		}
+		if decoded.MessageType() != msg.MessageType() {
+			t.Errorf("expected %s, got %s", msg.MessageType(), decoded.MessageType())
+		}
+		again, _ := json.Marshal(decoded)
+		if string(again) != string(data) {
+			t.Errorf("%s changed in round trip: %s != %s", msg.MessageType(), again, data)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package powerups models how power-ups appear and disappear during a game.
+//
+// Every tick a power-up appears on a random free tile with AddPowerUpLikelihood percent chance,
+// after which a random power-up disappears with RemovePowerUpLikelihood percent chance.
+package powerups
+
+import (
+	"math/rand"
+	"sort"
+
+	"paintbot-client/models"
+)
+
+// Model holds the chances of a power-up appearing and disappearing each tick
+type Model struct {
+	Add    float64
+	Remove float64
+}
+
+// NewModel returns the model for the settings, power-ups never appear or disappear if they are disabled
+func NewModel(settings models.GameSettings) Model {
+	if !settings.PowerUpsEnabled {
+		return Model{}
+	}
+	return Model{
+		Add:    float64(settings.AddPowerUpLikelihood) / 100,
+		Remove: float64(settings.RemovePowerUpLikelihood) / 100,
+	}
+}
+
+// Distribution returns the chance of there being n power-ups after the given number of ticks, for every n,
+// starting with current power-ups. Power-ups picked up by players are not accounted for.
+func (m Model) Distribution(current, ticks int) []float64 {
+	current, ticks = nonNegative(current), nonNegative(ticks)
+	dist := m.start(current, ticks)
+	for t := 0; t < ticks; t++ {
+		dist = m.step(dist)
+	}
+	return dist
+}
+
+// Expected returns the expected number of power-ups after each of the next ticks, starting with current
+// power-ups, empty if there are no ticks left. Power-ups picked up by players are not accounted for.
+func (m Model) Expected(current, ticks int) []float64 {
+	current, ticks = nonNegative(current), nonNegative(ticks)
+	expected := make([]float64, ticks)
+	dist := m.start(current, ticks)
+	for t := range expected {
+		dist = m.step(dist)
+		for n, p := range dist {
+			expected[t] += float64(n) * p
+		}
+	}
+	return expected
+}
+
+// RemainingTicks returns the number of ticks left of a game played with settings after the given tick, never negative
+func RemainingTicks(settings models.GameSettings, tick int) int {
+	if settings.TimeInMSPerTick <= 0 {
+		return 0
+	}
+	remaining := settings.GameDurationInSeconds*1000/settings.TimeInMSPerTick - tick
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// ExpectedForRestOfGame returns the expected number of power-ups after each tick left of the game,
+// starting with current power-ups at the given tick
+func ExpectedForRestOfGame(settings models.GameSettings, current, tick int) []float64 {
+	return NewModel(settings).Expected(current, RemainingTicks(settings, tick))
+}
+
+// start returns the distribution of current power-ups, with room for one more every tick
+func (m Model) start(current, ticks int) []float64 {
+	dist := make([]float64, current+ticks+1)
+	dist[current] = 1
+	return dist
+}
+
+// step returns the distribution after one more tick, the last count must have zero chance
+func (m Model) step(dist []float64) []float64 {
+	added := make([]float64, len(dist))
+	for n, p := range dist {
+		if p == 0 {
+			continue
+		}
+		added[n+1] += p * m.Add
+		added[n] += p * (1 - m.Add)
+	}
+
+	next := make([]float64, len(dist))
+	next[0] = added[0]
+	for n := 1; n < len(added); n++ {
+		next[n] += added[n] * (1 - m.Remove)
+		next[n-1] += added[n] * m.Remove
+	}
+	return next
+}
+
+func nonNegative(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Spawner adds and removes power-ups tick by tick, the same seed always gives the same changes
+type Spawner struct {
+	Model
+	rng *rand.Rand
+}
+
+// NewSpawner returns a spawner following the settings
+func NewSpawner(settings models.GameSettings, seed int64) *Spawner {
+	return &Spawner{Model: NewModel(settings), rng: rand.New(rand.NewSource(seed))}
+}
+
+// Step plays one tick, maybe adding a power-up on one of the free tiles and maybe removing one.
+// It returns the power-ups after the tick, sorted.
+func (s *Spawner) Step(powerUps []int, free []int) []int {
+	result := append([]int{}, powerUps...)
+	if len(free) > 0 && s.rng.Float64() < s.Add {
+		result = append(result, free[s.rng.Intn(len(free))])
+	}
+	if len(result) > 0 && s.rng.Float64() < s.Remove {
+		i := s.rng.Intn(len(result))
+		result = append(result[:i], result[i+1:]...)
+	}
+	sort.Ints(result)
+	return result
+}
@@ -0,0 +1,117 @@
+package powerups
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"paintbot-client/models"
+)
+
+func TestNewModel(t *testing.T) {
+	settings := models.GameSettings{PowerUpsEnabled: true, AddPowerUpLikelihood: 38, RemovePowerUpLikelihood: 5}
+	if m := NewModel(settings); m.Add != 0.38 || m.Remove != 0.05 {
+		t.Errorf("unexpected model %+v", m)
+	}
+	settings.PowerUpsEnabled = false
+	if m := NewModel(settings); m.Add != 0 || m.Remove != 0 {
+		t.Errorf("expected no power-ups when disabled, got %+v", m)
+	}
+}
+
+func TestModel_Expected(t *testing.T) {
+	// without removals, power-ups add up
+	expected := Model{Add: 0.5}.Expected(2, 4)
+	if !reflect.DeepEqual(expected, []float64{2.5, 3, 3.5, 4}) {
+		t.Errorf("unexpected %v", expected)
+	}
+	// always removed, never added, until there are none left
+	expected = Model{Remove: 1}.Expected(2, 3)
+	if !reflect.DeepEqual(expected, []float64{1, 0, 0}) {
+		t.Errorf("unexpected %v", expected)
+	}
+}
+
+func TestModel_Distribution(t *testing.T) {
+	dist := Model{Add: 0.5, Remove: 0.5}.Distribution(0, 1)
+	// added and kept 0.25, otherwise none
+	if math.Abs(dist[0]-0.75) > 1e-9 || math.Abs(dist[1]-0.25) > 1e-9 {
+		t.Errorf("unexpected %v", dist)
+	}
+
+	sum := 0.0
+	for _, p := range (Model{Add: 0.38, Remove: 0.05}).Distribution(10, 100) {
+		sum += p
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("probabilities sum to %f", sum)
+	}
+}
+
+func TestSpawner_matchesModel(t *testing.T) {
+	settings := models.GameSettings{PowerUpsEnabled: true, AddPowerUpLikelihood: 38, RemovePowerUpLikelihood: 20}
+	free := make([]int, 1000)
+	for i := range free {
+		free[i] = i
+	}
+
+	const runs, ticks = 2000, 30
+	s := NewSpawner(settings, 1)
+	total := 0
+	for r := 0; r < runs; r++ {
+		powerUps := []int{}
+		for tick := 0; tick < ticks; tick++ {
+			powerUps = s.Step(powerUps, free)
+		}
+		total += len(powerUps)
+	}
+
+	mean := float64(total) / runs
+	expected := s.Expected(0, ticks)[ticks-1]
+	if math.Abs(mean-expected) > 0.1 {
+		t.Errorf("expected about %f power-ups, spawner gave %f", expected, mean)
+	}
+}
+
+func TestSpawner_sameSeed(t *testing.T) {
+	settings := models.GameSettings{PowerUpsEnabled: true, AddPowerUpLikelihood: 50, RemovePowerUpLikelihood: 50}
+	play := func() []int {
+		s := NewSpawner(settings, 3)
+		powerUps := []int{}
+		for tick := 0; tick < 50; tick++ {
+			powerUps = s.Step(powerUps, []int{1, 2, 3, 4, 5, 6, 7, 8, 9})
+		}
+		return powerUps
+	}
+	if !reflect.DeepEqual(play(), play()) {
+		t.Error("same seed gave different power-ups")
+	}
+}
+
+func TestModel_noTicksLeft(t *testing.T) {
+	m := Model{Add: 0.5, Remove: 0.1}
+	if expected := m.Expected(3, -5); len(expected) != 0 {
+		t.Errorf("expected nothing for negative ticks, got %v", expected)
+	}
+	if dist := m.Distribution(2, -1); len(dist) != 3 || dist[2] != 1 {
+		t.Errorf("expected the current count for negative ticks, got %v", dist)
+	}
+}
+
+func TestExpectedForRestOfGame(t *testing.T) {
+	settings := models.GameSettings{PowerUpsEnabled: true, AddPowerUpLikelihood: 50, TimeInMSPerTick: 250, GameDurationInSeconds: 10}
+	if remaining := RemainingTicks(settings, 30); remaining != 10 {
+		t.Errorf("expected 10 ticks left, got %d", remaining)
+	}
+	if remaining := RemainingTicks(settings, 45); remaining != 0 {
+		t.Errorf("expected no ticks left after the end, got %d", remaining)
+	}
+
+	expected := ExpectedForRestOfGame(settings, 1, 36)
+	if !reflect.DeepEqual(expected, []float64{1.5, 2, 2.5, 3}) {
+		t.Errorf("unexpected %v", expected)
+	}
+	if expected := ExpectedForRestOfGame(settings, 1, 50); len(expected) != 0 {
+		t.Errorf("expected nothing after the end, got %v", expected)
+	}
+}